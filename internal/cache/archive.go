@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsArchive reports whether path names a template archive go-ten can extract
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract unpacks a .zip, .tar, .tar.gz or .tgz archive into dir and returns
+// the directory holding its contents. Archives with a single top level
+// directory, as made by "git archive --prefix" or GitHub, yield that directory.
+func Extract(archive, dir string) (string, error) {
+	var err error
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		err = extractZip(archive, dir)
+	} else {
+		err = extractTar(archive, dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to extract %s: %w", archive, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract %s: %w", archive, err)
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
+
+// extractZip unpacks a zip archive into dir
+func extractZip(archive, dir string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			if err := makeArchiveDir(dir, f.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		if !f.Mode().IsRegular() {
+			return fmt.Errorf("unsupported file type: %s", f.Name)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArchiveFile(dir, f.Name, f.Mode(), rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractTar unpacks a tar archive, gzip compressed unless it ends in .tar
+func extractTar(archive, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if !strings.HasSuffix(strings.ToLower(archive), ".tar") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := makeArchiveDir(dir, hdr.Name); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(dir, hdr.Name, hdr.FileInfo().Mode(), tr); err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader:
+			// "git archive" stores the commit here
+		default:
+			return fmt.Errorf("unsupported file type: %s", hdr.Name)
+		}
+	}
+}
+
+// archivePath returns where an archive member is extracted to, rejecting
+// names that would leave dir
+func archivePath(dir, name string) (string, error) {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" || !filepath.IsLocal(filepath.FromSlash(name)) || strings.Contains(name, `\`) {
+		return "", fmt.Errorf("unsafe path in archive: %s", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(name)), nil
+}
+
+// makeArchiveDir creates a directory member below dir
+func makeArchiveDir(dir, name string) error {
+	path, err := archivePath(dir, name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(path, 0755)
+}
+
+// writeArchiveFile writes an archive member below dir
+func writeArchiveFile(dir, name string, mode os.FileMode, r io.Reader) error {
+	path, err := archivePath(dir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,21 @@
+// Package cache stores templates fetched from remote sources on the local disk
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the go-ten cache directory, $XDG_CACHE_HOME/go-ten or
+// ~/.cache/go-ten when it is not set
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "go-ten"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the cache directory: %w", err)
+	}
+	return filepath.Join(home, ".cache", "go-ten"), nil
+}
@@ -0,0 +1,301 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitPrefix marks a template source as a git repository, e.g.
+// "git+https://github.com/ourorg/templates.git@v1.2.0"
+const GitPrefix = "git+"
+
+// GitSource is a git repository and the ref to check out
+type GitSource struct {
+	URL string
+	Ref string // branch, tag or commit; "HEAD" when not given
+}
+
+// IsGitSource reports whether spec names a git repository
+func IsGitSource(spec string) bool {
+	return strings.HasPrefix(spec, GitPrefix)
+}
+
+// ParseGitSource parses "git+<url>@<ref>". The ref follows the last "@"; it
+// may be left out for URLs without user info, e.g. "git+git@host:org/repo".
+func ParseGitSource(spec string) (GitSource, error) {
+	rest, ok := strings.CutPrefix(spec, GitPrefix)
+	if !ok || rest == "" {
+		return GitSource{}, fmt.Errorf("invalid git template %q: expected git+<url>@<ref>", spec)
+	}
+
+	source := GitSource{URL: rest, Ref: "HEAD"}
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		// "git@host:org/repo" has no ref: the part after "@" is the host
+		if ref := rest[i+1:]; !strings.Contains(ref, ":") {
+			source.URL, source.Ref = rest[:i], ref
+		}
+	}
+	if source.URL == "" || source.Ref == "" || strings.HasPrefix(source.Ref, "-") {
+		return GitSource{}, fmt.Errorf("invalid git template %q: expected git+<url>@<ref>", spec)
+	}
+	return source, nil
+}
+
+// String returns the source in "git+<url>@<ref>" form
+func (s GitSource) String() string {
+	return GitPrefix + s.URL + "@" + s.Ref
+}
+
+// Checkout is a ref of a git repository checked out in the cache
+type Checkout struct {
+	Source GitSource
+	Commit string // full commit hash the ref resolved to
+	Dir    string // files of the commit, without the .git directory
+}
+
+// fullCommit matches a full commit hash, which never needs fetching once known
+var fullCommit = regexp.MustCompile(`^[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// checkouts remembers the checkouts made by this process, so a branch is
+// fetched at most once per run
+var checkouts = struct {
+	sync.Mutex
+	bySpec map[string]*Checkout
+}{bySpec: map[string]*Checkout{}}
+
+// FetchGit mirrors the repository of source into the cache, resolves the ref
+// and returns a checkout of it. Tags and commits already in the cache are used
+// without contacting the remote; branches are fetched, falling back to the
+// cached commit when the remote cannot be reached.
+func FetchGit(source GitSource) (*Checkout, error) {
+	checkouts.Lock()
+	defer checkouts.Unlock()
+
+	if checkout, ok := checkouts.bySpec[source.String()]; ok {
+		return checkout, nil
+	}
+
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	repoDir := filepath.Join(root, "git", repoKey(source.URL))
+	mirror := filepath.Join(repoDir, "mirror.git")
+
+	// Clone the repository once, as a bare mirror of every ref
+	if !exists(mirror) {
+		if err := cloneMirror(source.URL, mirror); err != nil {
+			return nil, err
+		}
+	} else if !isPinned(mirror, source.Ref) {
+		// Refresh moving refs; offline the cached refs are used as they are
+		if err := runGit(mirror, "remote", "update", "--prune"); err != nil {
+			if _, resolveErr := resolveRef(mirror, source.Ref); resolveErr != nil {
+				return nil, fmt.Errorf("failed to fetch %s: %w", source.URL, err)
+			}
+		}
+	}
+
+	commit, err := resolveRef(mirror, source.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s in %s: %w", source.Ref, source.URL, err)
+	}
+
+	dir := filepath.Join(repoDir, "checkouts", commit)
+	if !exists(dir) {
+		if err := exportCommit(mirror, commit, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	touchMirror(repoDir, source.URL)
+	checkout := &Checkout{Source: source, Commit: commit, Dir: dir}
+	checkouts.bySpec[source.String()] = checkout
+	return checkout, nil
+}
+
+// mirrorURLFile holds the URL of a mirrored repository; its modification
+// time is when the repository was last used
+const mirrorURLFile = "url"
+
+// Mirror is a git repository mirrored into the cache, with its checkouts
+type Mirror struct {
+	URL      string // empty for mirrors of older go-ten versions
+	Dir      string
+	LastUsed time.Time
+}
+
+// touchMirror records the URL of the repository in repoDir and that it was
+// used now. Failing to do so only affects pruning, so errors are ignored.
+func touchMirror(repoDir, url string) {
+	os.WriteFile(filepath.Join(repoDir, mirrorURLFile), []byte(url+"\n"), 0644)
+}
+
+// Mirrors lists the git repositories mirrored into the cache
+func Mirrors() ([]Mirror, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := os.ReadDir(filepath.Join(root, "git"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git cache: %w", err)
+	}
+
+	var mirrors []Mirror
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		mirror := Mirror{Dir: filepath.Join(root, "git", d.Name())}
+		info, err := os.Stat(filepath.Join(mirror.Dir, mirrorURLFile))
+		if err == nil {
+			url, _ := os.ReadFile(filepath.Join(mirror.Dir, mirrorURLFile))
+			mirror.URL = strings.TrimSpace(string(url))
+		} else if info, err = d.Info(); err != nil {
+			return nil, fmt.Errorf("failed to read git cache: %w", err)
+		}
+		mirror.LastUsed = info.ModTime().UTC()
+		mirrors = append(mirrors, mirror)
+	}
+	return mirrors, nil
+}
+
+// pruneMirrors removes the mirrors not used since before
+func pruneMirrors(before time.Time) ([]Mirror, error) {
+	mirrors, err := Mirrors()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []Mirror
+	for _, mirror := range mirrors {
+		if !mirror.LastUsed.Before(before) {
+			continue
+		}
+		if err := os.RemoveAll(mirror.Dir); err != nil {
+			return removed, fmt.Errorf("failed to remove git mirror %s: %w", mirror.Dir, err)
+		}
+		removed = append(removed, mirror)
+	}
+	return removed, nil
+}
+
+// repoKey names the cache directory of a repository URL
+func repoKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:8])
+}
+
+// isPinned reports whether ref is a tag or full commit hash already in the mirror
+func isPinned(mirror, ref string) bool {
+	if fullCommit.MatchString(ref) {
+		_, err := resolveRef(mirror, ref)
+		return err == nil
+	}
+	_, err := gitOutput(mirror, "rev-parse", "--verify", "--quiet", "refs/tags/"+ref)
+	return err == nil
+}
+
+// cloneMirror clones url as a bare mirror, removing partial output on failure
+func cloneMirror(url, mirror string) error {
+	if err := os.MkdirAll(filepath.Dir(mirror), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(mirror), ".clone-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := runGit("", "clone", "--quiet", "--mirror", "--", url, tmp); err != nil {
+		return fmt.Errorf("failed to clone %s: %w", url, err)
+	}
+	if err := os.Rename(tmp, mirror); err != nil && !exists(mirror) {
+		return fmt.Errorf("failed to move clone into the cache: %w", err)
+	}
+	return nil
+}
+
+// resolveRef returns the commit hash ref points to in the repository
+func resolveRef(gitDir, ref string) (string, error) {
+	out, err := gitOutput(gitDir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown ref %s", ref)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// exportCommit writes the files of commit to dir without git metadata
+func exportCommit(mirror, commit, dir string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".checkout-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// A shared clone reuses the objects of the mirror instead of copying them
+	if err := runGit("", "clone", "--quiet", "--shared", "--no-checkout", "--", mirror, tmp); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", commit, err)
+	}
+	if err := runGit(tmp, "checkout", "--quiet", "--detach", commit); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", commit, err)
+	}
+	if err := os.RemoveAll(filepath.Join(tmp, ".git")); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", commit, err)
+	}
+
+	// Another process may have exported the same commit meanwhile
+	if err := os.Rename(tmp, dir); err != nil && !exists(dir) {
+		return fmt.Errorf("failed to move checkout into the cache: %w", err)
+	}
+	return nil
+}
+
+// exists reports whether path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// runGit runs git in dir, returning its error output on failure
+func runGit(dir string, args ...string) error {
+	_, err := gitOutput(dir, args...)
+	return err
+}
+
+// gitOutput runs git in dir and returns its standard output
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// Never wait for credentials on a terminal
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
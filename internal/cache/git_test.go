@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gitRepo is a work tree pushing to a local bare repository that acts as the remote
+type gitRepo struct {
+	t      *testing.T
+	work   string
+	remote string
+}
+
+// newGitRepo creates a repository with an empty isolated cache
+func newGitRepo(t *testing.T) *gitRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	resetCheckouts(t)
+
+	r := &gitRepo{t: t, work: t.TempDir(), remote: filepath.Join(t.TempDir(), "templates.git")}
+	r.git("", "init", "--quiet", "--bare", "-b", "main", r.remote)
+	r.git(r.work, "init", "--quiet", "-b", "main")
+	r.git(r.work, "remote", "add", "origin", r.remote)
+	return r
+}
+
+// resetCheckouts forgets the checkouts of earlier FetchGit calls
+func resetCheckouts(t *testing.T) {
+	checkouts.Lock()
+	checkouts.bySpec = map[string]*Checkout{}
+	checkouts.Unlock()
+}
+
+// git runs git in dir and fails the test on error
+func (r *gitRepo) git(dir string, args ...string) string {
+	r.t.Helper()
+	out, err := gitOutput(dir, args...)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return strings.TrimSpace(out)
+}
+
+// commit writes a file, commits and pushes it, returning the commit hash
+func (r *gitRepo) commit(name, content string) string {
+	r.t.Helper()
+	if err := os.WriteFile(filepath.Join(r.work, name), []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+	r.git(r.work, "add", name)
+	r.git(r.work, "commit", "--quiet", "-m", "update "+name)
+	r.git(r.work, "push", "--quiet", "origin", "main", "--tags")
+	return r.git(r.work, "rev-parse", "HEAD")
+}
+
+// tag tags HEAD and pushes the tag
+func (r *gitRepo) tag(name string) {
+	r.t.Helper()
+	r.git(r.work, "tag", name)
+	r.git(r.work, "push", "--quiet", "origin", name)
+}
+
+// readFile reads a file of a checkout
+func readFile(t *testing.T, checkout *Checkout, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(checkout.Dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestParseGitSource(t *testing.T) {
+	tests := map[string]GitSource{
+		"git+https://github.com/ourorg/templates.git@v1.2.0": {URL: "https://github.com/ourorg/templates.git", Ref: "v1.2.0"},
+		"git+git@github.com:ourorg/templates.git@main":       {URL: "git@github.com:ourorg/templates.git", Ref: "main"},
+		"git+git@github.com:ourorg/templates.git":            {URL: "git@github.com:ourorg/templates.git", Ref: "HEAD"},
+		"git+/srv/git/templates.git@release/1.x":             {URL: "/srv/git/templates.git", Ref: "release/1.x"},
+	}
+	for spec, want := range tests {
+		got, err := ParseGitSource(spec)
+		if err != nil || got != want {
+			t.Errorf("ParseGitSource(%q) = %+v, %v; want %+v", spec, got, err, want)
+		}
+	}
+
+	for _, spec := range []string{"git+", "git+@v1", "git+/srv/repo@", "git+/srv/repo@--upload-pack=x", "/srv/repo"} {
+		if _, err := ParseGitSource(spec); err == nil {
+			t.Errorf("ParseGitSource(%q) should have failed", spec)
+		}
+	}
+}
+
+func TestFetchGit(t *testing.T) {
+	r := newGitRepo(t)
+	first := r.commit("template.yaml", "version: 1\n")
+	r.tag("v1")
+	second := r.commit("template.yaml", "version: 2\n")
+
+	// A tag checks out the tagged commit, without git metadata
+	checkout, err := FetchGit(GitSource{URL: r.remote, Ref: "v1"})
+	if err != nil {
+		t.Fatalf("FetchGit failed: %v", err)
+	}
+	if checkout.Commit != first || readFile(t, checkout, "template.yaml") != "version: 1\n" {
+		t.Errorf("Unexpected checkout of v1: %+v", checkout)
+	}
+	if _, err := os.Stat(filepath.Join(checkout.Dir, ".git")); !os.IsNotExist(err) {
+		t.Error("checkout should not contain a .git directory")
+	}
+
+	// A branch follows the remote
+	checkout, err = FetchGit(GitSource{URL: r.remote, Ref: "main"})
+	if err != nil {
+		t.Fatalf("FetchGit failed: %v", err)
+	}
+	if checkout.Commit != second {
+		t.Errorf("main checked out %s, want %s", checkout.Commit, second)
+	}
+
+	resetCheckouts(t)
+	third := r.commit("template.yaml", "version: 3\n")
+	checkout, err = FetchGit(GitSource{URL: r.remote, Ref: "main"})
+	if err != nil {
+		t.Fatalf("FetchGit failed: %v", err)
+	}
+	if checkout.Commit != third {
+		t.Errorf("main checked out %s after a push, want %s", checkout.Commit, third)
+	}
+
+	// Commits can be pinned directly
+	checkout, err = FetchGit(GitSource{URL: r.remote, Ref: second})
+	if err != nil || readFile(t, checkout, "template.yaml") != "version: 2\n" {
+		t.Errorf("FetchGit of a commit failed: %v", err)
+	}
+
+	if _, err := FetchGit(GitSource{URL: r.remote, Ref: "missing"}); err == nil {
+		t.Error("FetchGit should fail for an unknown ref")
+	}
+}
+
+func TestFetchGitOffline(t *testing.T) {
+	r := newGitRepo(t)
+	commit := r.commit("template.yaml", "version: 1\n")
+	r.tag("v1")
+
+	for _, ref := range []string{"v1", "main"} {
+		if _, err := FetchGit(GitSource{URL: r.remote, Ref: ref}); err != nil {
+			t.Fatalf("FetchGit(%s) failed: %v", ref, err)
+		}
+	}
+
+	// The remote goes away: cached refs keep working
+	if err := os.RemoveAll(r.remote); err != nil {
+		t.Fatal(err)
+	}
+	resetCheckouts(t)
+
+	for _, ref := range []string{"v1", "main"} {
+		checkout, err := FetchGit(GitSource{URL: r.remote, Ref: ref})
+		if err != nil {
+			t.Fatalf("FetchGit(%s) offline failed: %v", ref, err)
+		}
+		if checkout.Commit != commit {
+			t.Errorf("FetchGit(%s) offline = %s, want %s", ref, checkout.Commit, commit)
+		}
+	}
+
+	if _, err := FetchGit(GitSource{URL: r.remote, Ref: "v2"}); err == nil {
+		t.Error("FetchGit should fail offline for a ref that was never fetched")
+	}
+}
+
+func TestPruneMirrors(t *testing.T) {
+	r := newGitRepo(t)
+	r.commit("template.yaml", "version: 1\n")
+	if _, err := FetchGit(GitSource{URL: r.remote, Ref: "main"}); err != nil {
+		t.Fatalf("FetchGit failed: %v", err)
+	}
+
+	mirrors, err := Mirrors()
+	if err != nil {
+		t.Fatalf("Mirrors failed: %v", err)
+	}
+	if len(mirrors) != 1 || mirrors[0].URL != r.remote || time.Since(mirrors[0].LastUsed) > time.Minute {
+		t.Fatalf("Unexpected mirrors: %+v", mirrors)
+	}
+
+	index, err := Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A recently used mirror is kept
+	if _, removed, err := index.Prune(time.Now().Add(-24 * time.Hour)); err != nil || len(removed) != 0 {
+		t.Fatalf("Prune removed %+v, %v", removed, err)
+	}
+
+	// An unused one is removed with its checkouts
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(mirrors[0].Dir, mirrorURLFile), old, old); err != nil {
+		t.Fatal(err)
+	}
+	_, removed, err := index.Prune(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0].URL != r.remote {
+		t.Errorf("Prune removed %+v, want the mirror of %s", removed, r.remote)
+	}
+	if _, err := os.Stat(mirrors[0].Dir); !os.IsNotExist(err) {
+		t.Error("Prune left the mirror behind")
+	}
+}
@@ -0,0 +1,305 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// indexFile lists the cached templates, relative to Dir
+const indexFile = "templates.json"
+
+// storeGrace is how long Prune leaves directories the index does not list
+// alone, as they may belong to a store still in progress
+const storeGrace = time.Hour
+
+// Entry describes a template in the cache
+type Entry struct {
+	ID       string    `json:"id"` // "{appType}-{package}"
+	Name     string    `json:"name"`
+	Version  string    `json:"version,omitempty"` // version from the template manifest
+	Source   string    `json:"source"`            // directory, archive or git source it was added from
+	Commit   string    `json:"commit,omitempty"`  // commit of git sources
+	Digest   string    `json:"digest"`            // hash of every file, checked before use
+	Added    time.Time `json:"added"`
+	LastUsed time.Time `json:"lastUsed,omitzero"`
+}
+
+// Index is the list of cached templates stored in the cache directory
+type Index struct {
+	Templates []Entry `json:"templates"`
+
+	root string // cache directory
+}
+
+// Open reads the index of the cache directory; a missing index is empty
+func Open() (*Index, error) {
+	root, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	index := &Index{root: root}
+	data, err := os.ReadFile(filepath.Join(root, indexFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template cache index: %w", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse template cache index: %w", err)
+	}
+	return index, nil
+}
+
+// Save writes the index, replacing the previous one atomically
+func (ix *Index) Save() error {
+	sort.Slice(ix.Templates, func(i, j int) bool {
+		return ix.Templates[i].ID < ix.Templates[j].ID
+	})
+
+	data, err := json.MarshalIndent(ix, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode template cache index: %w", err)
+	}
+	if err := os.MkdirAll(ix.root, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(ix.root, ".index-*")
+	if err != nil {
+		return fmt.Errorf("failed to write template cache index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write template cache index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write template cache index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(ix.root, indexFile)); err != nil {
+		return fmt.Errorf("failed to write template cache index: %w", err)
+	}
+	return nil
+}
+
+// Get returns the entry for a template ID
+func (ix *Index) Get(id string) (*Entry, bool) {
+	for i := range ix.Templates {
+		if ix.Templates[i].ID == id {
+			return &ix.Templates[i], true
+		}
+	}
+	return nil, false
+}
+
+// Path returns the directory holding the files of a cached template
+func (ix *Index) Path(id string) string {
+	return filepath.Join(ix.root, "templates", id)
+}
+
+// Store copies the template in dir into the cache as entry, replacing an
+// earlier version with the same ID, and saves the index
+func (ix *Index) Store(dir string, entry Entry) (*Entry, error) {
+	if entry.ID == "" || !filepath.IsLocal(entry.ID) || strings.ContainsAny(entry.ID, `/\`) {
+		return nil, fmt.Errorf("invalid template id %q", entry.ID)
+	}
+
+	// Copy next to the final directory, then swap it in
+	parent := filepath.Join(ix.root, "templates")
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(parent, ".store-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := copyDir(dir, tmp); err != nil {
+		return nil, fmt.Errorf("failed to copy template %s into the cache: %w", entry.ID, err)
+	}
+	digest, err := hashDir(tmp)
+	if err != nil {
+		return nil, err
+	}
+	entry.Digest = digest
+	entry.Added = time.Now().UTC()
+
+	target := ix.Path(entry.ID)
+	if err := os.RemoveAll(target); err != nil {
+		return nil, fmt.Errorf("failed to replace cached template %s: %w", entry.ID, err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return nil, fmt.Errorf("failed to move template %s into the cache: %w", entry.ID, err)
+	}
+
+	if existing, ok := ix.Get(entry.ID); ok {
+		entry.LastUsed = existing.LastUsed
+		*existing = entry
+	} else {
+		ix.Templates = append(ix.Templates, entry)
+	}
+	if err := ix.Save(); err != nil {
+		return nil, err
+	}
+
+	stored, _ := ix.Get(entry.ID)
+	return stored, nil
+}
+
+// Verify checks the files of a cached template against its recorded digest
+func (ix *Index) Verify(id string) error {
+	entry, ok := ix.Get(id)
+	if !ok {
+		return fmt.Errorf("template %s is not in the cache", id)
+	}
+
+	digest, err := hashDir(ix.Path(id))
+	if err != nil {
+		return err
+	}
+	if digest != entry.Digest {
+		return fmt.Errorf("cached template %s was modified (digest %s, expected %s); run 'go-ten template update %s'", id, digest, entry.Digest, id)
+	}
+	return nil
+}
+
+// Touch records that a cached template was used now and saves the index
+func (ix *Index) Touch(id string) error {
+	entry, ok := ix.Get(id)
+	if !ok {
+		return fmt.Errorf("template %s is not in the cache", id)
+	}
+	entry.LastUsed = time.Now().UTC()
+	return ix.Save()
+}
+
+// Remove deletes a cached template and saves the index
+func (ix *Index) Remove(id string) error {
+	for i := range ix.Templates {
+		if ix.Templates[i].ID == id {
+			if err := os.RemoveAll(ix.Path(id)); err != nil {
+				return fmt.Errorf("failed to remove cached template %s: %w", id, err)
+			}
+			ix.Templates = append(ix.Templates[:i], ix.Templates[i+1:]...)
+			return ix.Save()
+		}
+	}
+	return fmt.Errorf("template %s is not in the cache", id)
+}
+
+// Prune removes the templates not used since before, or added before it if
+// they were never used, together with files no entry refers to, and the git
+// mirrors not used since before. It returns what was removed.
+func (ix *Index) Prune(before time.Time) ([]Entry, []Mirror, error) {
+	var removed []Entry
+	for _, entry := range append([]Entry(nil), ix.Templates...) {
+		lastUsed := entry.LastUsed
+		if lastUsed.IsZero() {
+			lastUsed = entry.Added
+		}
+		if lastUsed.Before(before) {
+			if err := ix.Remove(entry.ID); err != nil {
+				return removed, nil, err
+			}
+			removed = append(removed, entry)
+		}
+	}
+
+	// Leftovers of interrupted stores and templates missing from the index.
+	// A template being added is copied to a .store-* directory and renamed
+	// before the index lists it, so recent directories are left alone.
+	dirs, err := os.ReadDir(filepath.Join(ix.root, "templates"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return removed, nil, fmt.Errorf("failed to read template cache: %w", err)
+	}
+	for _, d := range dirs {
+		if _, ok := ix.Get(d.Name()); ok {
+			continue
+		}
+		if info, err := d.Info(); err != nil || time.Since(info.ModTime()) < storeGrace {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(ix.root, "templates", d.Name())); err != nil {
+			return removed, nil, fmt.Errorf("failed to remove %s: %w", d.Name(), err)
+		}
+	}
+
+	// Cached templates are copies, so mirrors are only needed to fetch again
+	mirrors, err := pruneMirrors(before)
+	return removed, mirrors, err
+}
+
+// hashDir returns the "h1:" digest of every file below dir, as used by go.sum
+func hashDir(dir string) (string, error) {
+	digest, err := dirhash.HashDir(dir, "", dirhash.Hash1)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", dir, err)
+	}
+	return digest, nil
+}
+
+// copyDir copies the regular files and directories below src into dst,
+// leaving out git metadata
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		default:
+			return fmt.Errorf("unsupported file type: %s", rel)
+		}
+	})
+}
+
+// copyFile copies a regular file, keeping its permission bits
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
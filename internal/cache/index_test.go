@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFiles creates files below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// openIndex opens the index of an empty isolated cache
+func openIndex(t *testing.T) *Index {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	index, err := Open()
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return index
+}
+
+func TestIndexStore(t *testing.T) {
+	index := openIndex(t)
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{"template.yaml": "name: x\n", "a/b.txt": "b", ".git/HEAD": "ref"})
+
+	entry, err := index.Store(src, Entry{ID: "web-api-house", Name: "House", Version: "1.0.0", Source: src})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if entry.Digest == "" || entry.Added.IsZero() {
+		t.Errorf("Store did not record digest and time: %+v", entry)
+	}
+	if _, err := os.Stat(filepath.Join(index.Path(entry.ID), "a", "b.txt")); err != nil {
+		t.Error("Store did not copy the files")
+	}
+	if _, err := os.Stat(filepath.Join(index.Path(entry.ID), ".git")); !os.IsNotExist(err) {
+		t.Error("Store should leave out git metadata")
+	}
+
+	// The index survives reopening
+	reopened, err := Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reopened.Get("web-api-house"); !ok || got.Digest != entry.Digest || got.Version != "1.0.0" {
+		t.Errorf("Reopened index has %+v", got)
+	}
+
+	if _, err := index.Store(src, Entry{ID: "../escape"}); err == nil {
+		t.Error("Store should reject ids that are not a single path element")
+	}
+}
+
+func TestIndexVerify(t *testing.T) {
+	index := openIndex(t)
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{"template.yaml": "name: x\n"})
+
+	if _, err := index.Store(src, Entry{ID: "x-y", Source: src}); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Verify("x-y"); err != nil {
+		t.Errorf("Verify failed on untouched template: %v", err)
+	}
+
+	// Any change to the cached files is detected
+	writeFiles(t, index.Path("x-y"), map[string]string{"extra.txt": "x"})
+	if err := index.Verify("x-y"); err == nil {
+		t.Error("Verify should fail after the files changed")
+	}
+	if err := index.Verify("missing"); err == nil {
+		t.Error("Verify should fail for unknown templates")
+	}
+}
+
+func TestIndexPrune(t *testing.T) {
+	index := openIndex(t)
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{"template.yaml": "name: x\n"})
+
+	for _, id := range []string{"old-one", "used-one"} {
+		if _, err := index.Store(src, Entry{ID: id, Source: src}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := index.Touch("used-one"); err != nil {
+		t.Fatal(err)
+	}
+	old, _ := index.Get("old-one")
+	old.Added = time.Now().Add(-48 * time.Hour)
+	// A directory no entry refers to, left by an interrupted store, and one
+	// of a store still in progress
+	leftover := filepath.Join(index.root, "templates", ".store-123")
+	writeFiles(t, leftover, map[string]string{"x": "x"})
+	if err := os.Chtimes(leftover, time.Time{}, time.Now().Add(-2*storeGrace)); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, filepath.Join(index.root, "templates", ".store-456"), map[string]string{"x": "x"})
+
+	removed, _, err := index.Prune(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0].ID != "old-one" {
+		t.Errorf("Prune removed %+v, want old-one", removed)
+	}
+	if _, ok := index.Get("used-one"); !ok {
+		t.Error("Prune removed a recently used template")
+	}
+	for _, name := range []string{"old-one", ".store-123"} {
+		if _, err := os.Stat(filepath.Join(index.root, "templates", name)); !os.IsNotExist(err) {
+			t.Errorf("Prune left %s behind", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(index.root, "templates", ".store-456")); err != nil {
+		t.Errorf("Prune removed a store in progress: %v", err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+
+	// A .tar.gz with a single top level directory
+	tgz := filepath.Join(dir, "templates.tar.gz")
+	f, err := os.Create(tgz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{"templates-1.0/api/template.yaml": "name: x\n"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	root, err := Extract(tgz, filepath.Join(dir, "tgz"))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "api", "template.yaml")); err != nil {
+		t.Errorf("Extract should return the top level directory, got %s", root)
+	}
+
+	// A zip that tries to write outside the target
+	zipPath := filepath.Join(dir, "evil.zip")
+	f, err = os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("../evil.txt")
+	w.Write([]byte("x"))
+	zw.Close()
+	f.Close()
+
+	if _, err := Extract(zipPath, filepath.Join(dir, "zip")); err == nil {
+		t.Error("Extract should reject paths outside the target")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.txt")); !os.IsNotExist(err) {
+		t.Error("Extract wrote outside the target directory")
+	}
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK               = 0 // project generated or command succeeded
+	ExitGenerationFailed = 1 // generation or another runtime step failed
+	ExitValidation       = 2 // invalid flags, arguments or answers
+	ExitCancelled        = 3 // user quit the interactive prompts
+)
+
+// CLI holds the streams used by the command line interface
+type CLI struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Interactive reports whether the TUI may be started
+	Interactive bool
+}
+
+// New creates a CLI bound to the process standard streams
+func New() *CLI {
+	return &CLI{
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+		Interactive: isTerminal(os.Stdin) && isTerminal(os.Stdout),
+	}
+}
+
+// Run dispatches args (without the program name) to a subcommand and
+// returns the process exit code
+func (c *CLI) Run(args []string) int {
+	// Warnings about what generation worked around go with the errors
+	generator.Warnings = c.Stderr
+
+	// Without a subcommand, fall back to the fully interactive flow
+	if len(args) == 0 {
+		return c.runNew(nil)
+	}
+
+	switch args[0] {
+	case "new":
+		return c.runNew(args[1:])
+	case "update":
+		return c.runUpdate(args[1:])
+	case "diff":
+		return c.runDiff(args[1:])
+	case "template":
+		return c.runTemplate(args[1:])
+	case "help", "-h", "--help":
+		c.usage(c.Stdout)
+		return ExitOK
+	default:
+		fmt.Fprintf(c.Stderr, "unknown command %q\n\n", args[0])
+		c.usage(c.Stderr)
+		return ExitValidation
+	}
+}
+
+// usage prints the top level help
+func (c *CLI) usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: go-ten <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  new       Create a new project (interactive when values are missing)")
+	fmt.Fprintln(w, "  update    Re-apply the project's template, merging in local changes")
+	fmt.Fprintln(w, "  diff      Show how the project differs from its template today")
+	fmt.Fprintln(w, "  template  Manage cached templates (add, list, update, prune, info)")
+	fmt.Fprintln(w, "  help      Show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'go-ten <command> -h' for command flags.")
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
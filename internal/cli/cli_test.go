@@ -0,0 +1,369 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+// newTestCLI returns a non-interactive CLI writing to buffers
+func newTestCLI() (*CLI, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	return &CLI{Stdout: &stdout, Stderr: &stderr}, &stdout, &stderr
+}
+
+func TestRunNewWithFlags(t *testing.T) {
+	c, _, stderr := newTestCLI()
+	targetDir := filepath.Join(t.TempDir(), "svc")
+
+	code := c.Run([]string{"new", "--name", "my-api", "--type", "web-api", "--package", "stdlib", "--dir", targetDir})
+	if code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+
+	if _, err := os.Stat(filepath.Join(targetDir, "go.mod")); os.IsNotExist(err) {
+		t.Error("go.mod file was not created")
+	}
+}
+
+func TestRunNewValidationErrors(t *testing.T) {
+	tests := [][]string{
+		{"new", "--name", "my project", "--type", "web-api", "--package", "stdlib"},
+		{"new", "--name", "my-api", "--type", "invalid", "--package", "stdlib"},
+		{"new", "--name", "my-api", "--module", "/my-api", "--type", "web-api", "--package", "stdlib"},
+		{"new", "--name", "my-api"},
+		{"new", "--unknown"},
+		{"unknown"},
+	}
+
+	for _, args := range tests {
+		c, _, _ := newTestCLI()
+		if code := c.Run(args); code != ExitValidation {
+			t.Errorf("Expected exit code %d for %v, got %d", ExitValidation, args, code)
+		}
+	}
+}
+
+func TestRunNewGenerationFailure(t *testing.T) {
+	c, _, _ := newTestCLI()
+
+	// A regular file where the target directory should be created
+	targetDir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(targetDir, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code := c.Run([]string{"new", "--name", "my-api", "--type", "web-api", "--package", "stdlib", "--dir", targetDir})
+	if code != ExitGenerationFailed {
+		t.Errorf("Expected exit code %d, got %d", ExitGenerationFailed, code)
+	}
+}
+
+func TestRunNewWithConfigFile(t *testing.T) {
+	c, _, stderr := newTestCLI()
+	dir := t.TempDir()
+	targetDir := filepath.Join(dir, "svc")
+
+	answers := "projectName: my-api\nmoduleName: github.com/ourorg/my-api\nappType: web-api\npackage: stdlib\n"
+	configPath := filepath.Join(dir, "go-ten.yaml")
+	if err := os.WriteFile(configPath, []byte(answers), 0644); err != nil {
+		t.Fatal(err)
+	}
+	savePath := filepath.Join(dir, "saved.json")
+
+	code := c.Run([]string{"new", "--config", configPath, "--dir", targetDir, "--save-config", savePath})
+	if code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+
+	content, err := os.ReadFile(filepath.Join(targetDir, "go.mod"))
+	if err != nil {
+		t.Fatalf("Failed to read go.mod: %v", err)
+	}
+	if !bytes.Contains(content, []byte("module github.com/ourorg/my-api")) {
+		t.Errorf("go.mod does not use the module name from the answers file: %s", content)
+	}
+
+	if _, err := os.Stat(savePath); os.IsNotExist(err) {
+		t.Error("answers were not saved")
+	}
+}
+
+func TestRunNewWithModule(t *testing.T) {
+	c, _, stderr := newTestCLI()
+	targetDir := filepath.Join(t.TempDir(), "svc")
+
+	code := c.Run([]string{"new", "--name", "my-api", "--module", "example.com/ourorg/my-api", "--type", "web-api", "--package", "stdlib", "--dir", targetDir})
+	if code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+
+	// The module path is used for go.mod and imports, the name for the directory layout
+	content, err := os.ReadFile(filepath.Join(targetDir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(content, []byte("module example.com/ourorg/my-api\n")) {
+		t.Errorf("go.mod does not use the module path: %s", content)
+	}
+	content, err = os.ReadFile(filepath.Join(targetDir, "cmd", "my-api", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(content, []byte(`"example.com/ourorg/my-api/`)) {
+		t.Errorf("main.go does not import packages below the module path:\n%s", content)
+	}
+}
+
+func TestRunNewWithTemplateDir(t *testing.T) {
+	t.Setenv(generator.TemplatePathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	templateDir := t.TempDir()
+	manifest := "name: Worker\nappType: worker\npackage: stdlib\n"
+	if err := os.WriteFile(filepath.Join(templateDir, generator.ManifestFile), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "go.mod.tmpl"), []byte("module {{.ModuleName}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "main.go"), []byte("package main\n\nfunc main() {\n\tx :=\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The only template in the directory is selected without --type and --package
+	c, _, stderr := newTestCLI()
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	if code := c.Run([]string{"new", "--name", "my-worker", "--template", templateDir, "--dir", targetDir}); code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "go.mod")); err != nil {
+		t.Error("go.mod from the template directory was not created")
+	}
+	if !strings.Contains(stderr.String(), "Warning: main.go was written unformatted: ") {
+		t.Errorf("Expected a warning about main.go:\n%s", stderr.String())
+	}
+
+	c, _, _ = newTestCLI()
+	if code := c.Run([]string{"new", "--name", "my-worker", "--template", filepath.Join(templateDir, "missing")}); code != ExitValidation {
+		t.Errorf("Expected exit code %d for a missing template directory, got %d", ExitValidation, code)
+	}
+}
+
+func TestRunNewOnConflict(t *testing.T) {
+	targetDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(targetDir, "go.mod"), []byte("module existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"new", "--name", "my-api", "--type", "web-api", "--package", "stdlib", "--dir", targetDir}
+
+	c, _, _ := newTestCLI()
+	if code := c.Run(args); code != ExitGenerationFailed {
+		t.Errorf("Expected exit code %d without a policy, got %d", ExitGenerationFailed, code)
+	}
+
+	c, _, _ = newTestCLI()
+	if code := c.Run(append(args, "--on-conflict", "bogus")); code != ExitValidation {
+		t.Errorf("Expected exit code %d for an invalid policy, got %d", ExitValidation, code)
+	}
+
+	// go mod tidy would rewrite the kept go.mod
+	c, _, stderr := newTestCLI()
+	if code := c.Run(append(args, "--on-conflict", "skip", "--post-generate", "none")); code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+	content, _ := os.ReadFile(filepath.Join(targetDir, "go.mod"))
+	if string(content) != "module existing\n" {
+		t.Error("go.mod should have been skipped")
+	}
+}
+
+func TestRunNewWithVars(t *testing.T) {
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	args := []string{"new", "--name", "my-api", "--type", "web-api", "--package", "stdlib", "--dir", targetDir}
+
+	c, _, _ := newTestCLI()
+	if code := c.Run(append(args, "--var", "port=abc")); code != ExitValidation {
+		t.Errorf("Expected exit code %d for an invalid variable, got %d", ExitValidation, code)
+	}
+
+	c, _, stderr := newTestCLI()
+	if code := c.Run(append(args, "--var", "port=9090")); code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+
+	content, err := os.ReadFile(filepath.Join(targetDir, "utils", "constants.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(content, []byte(`"9090"`)) {
+		t.Error("port variable was not applied")
+	}
+}
+
+func TestRunNewPostSteps(t *testing.T) {
+	t.Setenv(generator.TemplatePathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	templateDir := t.TempDir()
+	manifest := `name: Worker
+appType: worker
+package: stdlib
+postGenerate:
+  - name: bump
+    run: go mod edit -go=1.22
+    default: true
+`
+	files := map[string]string{
+		generator.ManifestFile: manifest,
+		"go.mod.tmpl":          "module {{.ModuleName}}\n\ngo 1.21\n",
+		"main.go":              "package main\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(templateDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The template's default step is only a suggestion
+	c, stdout, stderr := newTestCLI()
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	if code := c.Run([]string{"new", "--name", "my-worker", "--template", templateDir, "--dir", targetDir}); code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+	if strings.Contains(stdout.String(), "Running") {
+		t.Errorf("Expected no step to run without --post-generate:\n%s", stdout.String())
+	}
+
+	// Named steps run in the new project
+	c, stdout, stderr = newTestCLI()
+	targetDir = filepath.Join(t.TempDir(), "svc")
+	if code := c.Run([]string{"new", "--name", "my-worker", "--template", templateDir, "--dir", targetDir, "--post-generate", "bump"}); code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Running go mod edit -go=1.22") {
+		t.Errorf("Expected the step to be reported:\n%s", stdout.String())
+	}
+	if content, _ := os.ReadFile(filepath.Join(targetDir, "go.mod")); !strings.Contains(string(content), "go 1.22") {
+		t.Errorf("The post-generation step did not run:\n%s", content)
+	}
+
+	// A failing step fails the command, but the project stays
+	if err := os.WriteFile(filepath.Join(templateDir, "main.go"), []byte("package main\n\nfunc main() { broken }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, _, stderr = newTestCLI()
+	targetDir = filepath.Join(t.TempDir(), "svc")
+	if code := c.Run([]string{"new", "--name", "my-worker", "--template", templateDir, "--dir", targetDir, "--post-generate", "build"}); code != ExitGenerationFailed {
+		t.Errorf("Expected exit code %d for a failing step, got %d", ExitGenerationFailed, code)
+	}
+	if !strings.Contains(stderr.String(), "post-generation step build failed") {
+		t.Errorf("Expected the failing step to be reported:\n%s", stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "main.go")); err != nil {
+		t.Error("The project should be kept when a step fails")
+	}
+
+	c, _, _ = newTestCLI()
+	if code := c.Run([]string{"new", "--name", "my-worker", "--template", templateDir, "--post-generate", "deploy", "--dry-run"}); code != ExitValidation {
+		t.Errorf("Expected exit code %d for an unknown step, got %d", ExitValidation, code)
+	}
+}
+
+func TestRunNewGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	c, stdout, stderr := newTestCLI()
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	args := []string{"new", "--name", "my-api", "--type", "web-api", "--package", "stdlib", "--dir", targetDir,
+		"--post-generate", "none", "--git-branch", "main", "--git-author", "Jane Doe <jane@example.com>"}
+	if code := c.Run(args); code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "$ git commit") {
+		t.Errorf("Expected the git commands to be reported:\n%s", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, ".gitignore")); err != nil {
+		t.Error(".gitignore was not created")
+	}
+	cmd := exec.Command("git", "log", "-1", "--format=%an %D")
+	cmd.Dir = targetDir
+	if out, err := cmd.Output(); err != nil || !strings.HasPrefix(string(out), "Jane Doe HEAD -> main") {
+		t.Errorf("Expected an initial commit on main by Jane Doe, got %q (%v)", out, err)
+	}
+
+	// The project is its own work tree now, but still plans its .gitignore
+	c, stdout, stderr = newTestCLI()
+	if code := c.Run([]string{"diff", "--dir", targetDir, "--stat"}); code != ExitOK {
+		t.Fatalf("diff: exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), " 0 file(s) differ") {
+		t.Errorf("A freshly generated project should not differ from its template:\n%s", stdout.String())
+	}
+	c, stdout, stderr = newTestCLI()
+	if code := c.Run([]string{"update", "--dir", targetDir}); code != ExitOK {
+		t.Fatalf("update: exit code %d: %s", code, stderr.String())
+	}
+	if strings.Contains(stdout.String(), ".gitignore") {
+		t.Errorf("update should leave .gitignore alone:\n%s", stdout.String())
+	}
+	for _, path := range []string{".gitignore", filepath.Join(generator.BaseDir, ".gitignore")} {
+		if _, err := os.Stat(filepath.Join(targetDir, path)); err != nil {
+			t.Errorf("%s should be kept: %v", path, err)
+		}
+	}
+
+	c, _, _ = newTestCLI()
+	if code := c.Run([]string{"new", "--name", "my-api", "--type", "web-api", "--package", "stdlib", "--git-author", "jane", "--dry-run"}); code != ExitValidation {
+		t.Errorf("Expected exit code %d for an invalid author, got %d", ExitValidation, code)
+	}
+}
+
+func TestRunNewVerify(t *testing.T) {
+	t.Setenv(generator.TemplatePathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	templateDir := t.TempDir()
+	files := map[string]string{
+		generator.ManifestFile: "name: Worker\nappType: worker\npackage: stdlib\n",
+		"go.mod.tmpl":          "module {{.ModuleName}}\n\ngo 1.21\n",
+		"main.go.tmpl":         "package main\n\nimport \"{{.ModuleName}}/jobs\"\n\nfunc main() {\n\tjobs.Run()\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(templateDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, _, stderr := newTestCLI()
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	if code := c.Run([]string{"new", "--name", "my-worker", "--template", templateDir, "--dir", targetDir, "--verify", "syntax"}); code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", ExitOK, code, stderr.String())
+	}
+
+	// A broken template fails verification, reported by file and line
+	if err := os.WriteFile(filepath.Join(templateDir, "main.go.tmpl"), []byte("package main\n\nfunc main() {\n\tx :=\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, _, stderr = newTestCLI()
+	targetDir = filepath.Join(t.TempDir(), "svc")
+	if code := c.Run([]string{"new", "--name", "my-worker", "--template", templateDir, "--dir", targetDir, "--verify", "syntax"}); code != ExitGenerationFailed {
+		t.Errorf("Expected exit code %d, got %d", ExitGenerationFailed, code)
+	}
+	if !strings.Contains(stderr.String(), "main.go:5:1: expected operand") {
+		t.Errorf("Expected the problem by file and line:\n%s", stderr.String())
+	}
+
+	c, _, _ = newTestCLI()
+	if code := c.Run([]string{"new", "--name", "my-worker", "--template", templateDir, "--verify", "lint", "--dry-run"}); code != ExitValidation {
+		t.Errorf("Expected exit code %d for an unknown level, got %d", ExitValidation, code)
+	}
+}
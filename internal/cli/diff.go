@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+// diffOptions holds the flags of the diff command
+type diffOptions struct {
+	dir         string
+	name        string
+	module      string
+	appType     string
+	packageName string
+	template    string
+	vars        varsFlag
+	stat        bool
+	json        bool
+}
+
+// runDiff implements "go-ten diff"
+func (c *CLI) runDiff(args []string) int {
+	var opts diffOptions
+
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	fs.StringVar(&opts.dir, "dir", ".", "project directory")
+	fs.StringVar(&opts.name, "name", "", "project name (default from "+generator.LockFile+" or go.mod)")
+	fs.StringVar(&opts.module, "module", "", "Go module path (default from "+generator.LockFile+" or go.mod)")
+	fs.StringVar(&opts.appType, "type", "", "application type (default from "+generator.LockFile+", else the only matching template)")
+	fs.StringVar(&opts.packageName, "package", "", "package set (default from "+generator.LockFile+", else the only matching template)")
+	fs.StringVar(&opts.template, "template", "", "template source to compare with (default from "+generator.LockFile+", else the built-in templates)")
+	fs.Var(&opts.vars, "var", "template variable as key=value (repeatable)")
+	fs.BoolVar(&opts.stat, "stat", false, "print a summary of the changed files instead of the diff")
+	fs.BoolVar(&opts.json, "json", false, "print the result as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitValidation
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(c.Stderr, "unexpected arguments: %v\n", fs.Args())
+		return ExitValidation
+	}
+
+	config, err := opts.config()
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitValidation
+	}
+
+	result, err := generator.Drift(config)
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+
+	switch {
+	case opts.json:
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "Error: failed to encode result: %v\n", err)
+			return ExitGenerationFailed
+		}
+		fmt.Fprintln(c.Stdout, string(data))
+	case opts.stat:
+		c.printDiffStat(result)
+	default:
+		for _, f := range result.Files {
+			fmt.Fprint(c.Stdout, f.Diff)
+		}
+		if len(result.Files) == 0 {
+			fmt.Fprintf(c.Stdout, "No differences from %s %s\n", result.Template.ID, orDash(templateVersion(result.Template)))
+		}
+	}
+	return ExitOK
+}
+
+// config infers the answers from the project and applies the flags on top
+func (o diffOptions) config() (generator.ProjectConfig, error) {
+	config, err := generator.InferConfig(o.dir)
+	if err != nil {
+		if o.name == "" || o.module == "" {
+			return generator.ProjectConfig{}, fmt.Errorf("%w; pass --name and --module", err)
+		}
+		config = generator.ProjectConfig{TargetDir: o.dir}
+	}
+
+	if o.name != "" {
+		config.ProjectName = o.name
+	}
+	if o.module != "" {
+		config.ModuleName = o.module
+	}
+	if o.appType != "" {
+		config.AppType = o.appType
+	}
+	if o.packageName != "" {
+		config.Package = o.packageName
+	}
+	if o.template != "" {
+		config.Template = o.template
+	}
+	if len(o.vars) > 0 {
+		vars := make(map[string]any, len(config.Vars)+len(o.vars))
+		for name, value := range config.Vars {
+			vars[name] = value
+		}
+		for name, value := range o.vars {
+			vars[name] = value
+		}
+		config.Vars = vars
+	}
+
+	if config.AppType == "" || config.Package == "" {
+		if err := inferTemplate(&config); err != nil {
+			return generator.ProjectConfig{}, fmt.Errorf("no %s in %s: %w", generator.LockFile, o.dir, err)
+		}
+	}
+	return config, nil
+}
+
+// inferTemplate fills in the app type and package of config when exactly one
+// available template matches the parts already set
+func inferTemplate(config *generator.ProjectConfig) error {
+	templates, err := generator.TemplatesIn(config.Template)
+	if err != nil {
+		return err
+	}
+
+	var matches []generator.Template
+	for _, tmpl := range templates {
+		if (config.AppType == "" || tmpl.AppType == config.AppType) && (config.Package == "" || tmpl.Package == config.Package) {
+			matches = append(matches, tmpl)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return errors.New("no template matches; pass --type and --package")
+	case 1:
+		config.AppType, config.Package = matches[0].AppType, matches[0].Package
+		return nil
+	}
+
+	ids := make([]string, len(matches))
+	for i, tmpl := range matches {
+		ids[i] = tmpl.ID()
+	}
+	return fmt.Errorf("it could be any of %s; pass --type and --package", strings.Join(ids, ", "))
+}
+
+// printDiffStat prints one line per changed file with a bar of its added and
+// deleted lines, like git diff --stat
+func (c *CLI) printDiffStat(result *generator.DriftResult) {
+	const barWidth = 40
+
+	width, most := 0, 0
+	for _, f := range result.Files {
+		width = max(width, len(f.Path))
+		most = max(most, f.Added+f.Deleted)
+	}
+
+	added, deleted := 0, 0
+	for _, f := range result.Files {
+		added += f.Added
+		deleted += f.Deleted
+
+		plus, minus := f.Added, f.Deleted
+		if most > barWidth {
+			plus = (plus*barWidth + most - 1) / most
+			minus = (minus*barWidth + most - 1) / most
+		}
+		fmt.Fprintf(c.Stdout, " %-*s | %4d %s%s", width, f.Path, f.Added+f.Deleted, strings.Repeat("+", plus), strings.Repeat("-", minus))
+		if f.Status != generator.DriftModified {
+			fmt.Fprintf(c.Stdout, " (%s)", f.Status)
+		}
+		fmt.Fprintln(c.Stdout)
+	}
+
+	fmt.Fprintf(c.Stdout, " %d file(s) differ, %d insertion(s)(+), %d deletion(s)(-), %d unchanged\n", len(result.Files), added, deleted, result.Unchanged)
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+func TestRunDiff(t *testing.T) {
+	t.Setenv(generator.TemplatePathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	c, _, stderr := newTestCLI()
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	if code := c.Run([]string{"new", "--name", "my-api", "--module", "example.com/my-api", "--type", "web-api", "--package", "stdlib", "--dir", targetDir}); code != ExitOK {
+		t.Fatalf("new: exit code %d: %s", code, stderr.String())
+	}
+	if err := os.WriteFile(filepath.Join(targetDir, "go.mod"), []byte("module example.com/my-api\n\ngo 1.24\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, stdout, stderr := newTestCLI()
+	if code := c.Run([]string{"diff", "--dir", targetDir}); code != ExitOK {
+		t.Fatalf("diff: exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "--- a/go.mod\n+++ b/go.mod\n") || !strings.Contains(stdout.String(), "+go 1.24\n") {
+		t.Errorf("diff should show the go.mod change:\n%s", stdout.String())
+	}
+
+	c, stdout, _ = newTestCLI()
+	if code := c.Run([]string{"diff", "--dir", targetDir, "--stat"}); code != ExitOK {
+		t.Fatalf("diff --stat: exit code %d", code)
+	}
+	if !strings.Contains(stdout.String(), " go.mod | ") || !strings.Contains(stdout.String(), "1 file(s) differ") {
+		t.Errorf("Unexpected stat output:\n%s", stdout.String())
+	}
+
+	// Without the lockfile the answers come from go.mod and the only template
+	if err := os.Remove(filepath.Join(targetDir, generator.LockFile)); err != nil {
+		t.Fatal(err)
+	}
+	c, stdout, stderr = newTestCLI()
+	if code := c.Run([]string{"diff", "--dir", targetDir, "--json"}); code != ExitOK {
+		t.Fatalf("diff --json: exit code %d: %s", code, stderr.String())
+	}
+	var result generator.DriftResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("diff --json printed invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if result.Config.ProjectName != "my-api" || len(result.Files) != 1 || result.Files[0].Path != "go.mod" {
+		t.Errorf("Unexpected JSON result: %+v", result)
+	}
+
+	// With several templates to choose from, --type and --package decide
+	templateDir := t.TempDir()
+	manifest := "name: Web API\nappType: web-api\npackage: chi\n"
+	if err := os.WriteFile(filepath.Join(templateDir, generator.ManifestFile), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(generator.TemplatePathEnv, templateDir)
+	c, _, stderr = newTestCLI()
+	if code := c.Run([]string{"diff", "--dir", targetDir}); code != ExitValidation || !strings.Contains(stderr.String(), "web-api-chi, web-api-stdlib") {
+		t.Errorf("diff with two templates: exit code %d, want %d: %s", code, ExitValidation, stderr.String())
+	}
+	c, _, stderr = newTestCLI()
+	if code := c.Run([]string{"diff", "--dir", targetDir, "--package", "stdlib"}); code != ExitOK {
+		t.Errorf("diff --package stdlib: exit code %d: %s", code, stderr.String())
+	}
+}
@@ -0,0 +1,541 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/manuelbamise/go-ten/internal/generator"
+	"github.com/manuelbamise/go-ten/internal/prompts"
+)
+
+// newOptions holds the flags of the new command
+type newOptions struct {
+	name        string
+	module      string
+	appType     string
+	packageName string
+	template    string
+	dir         string
+	configFile  string
+	saveConfig  string
+	dryRun      bool
+	onConflict  string
+	postSteps   string
+	git         bool
+	gitBranch   string
+	gitAuthor   string
+	verify      string
+	vars        varsFlag
+
+	// answers loaded from configFile
+	answers generator.ProjectConfig
+}
+
+// runNew implements "go-ten new"
+func (c *CLI) runNew(args []string) int {
+	var opts newOptions
+
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	fs.StringVar(&opts.name, "name", "", "project name, or '.' for the current directory")
+	fs.StringVar(&opts.module, "module", "", "Go module path, e.g. github.com/ourorg/my-api (default from $"+generator.ModulePrefixEnv+", the git remote or GOPATH)")
+	fs.StringVar(&opts.appType, "type", "", "application type, e.g. web-api")
+	fs.StringVar(&opts.packageName, "package", "", "package set, e.g. stdlib")
+	fs.StringVar(&opts.template, "template", "", "template directory, directory of templates or git+<url>@<ref>, searched before $"+generator.TemplatePathEnv+", ~/.config/go-ten/templates and the built-in ones")
+	fs.StringVar(&opts.dir, "dir", "", "target directory (default ./<name>/)")
+	fs.StringVar(&opts.configFile, "config", "", "YAML or JSON answers file; flags override its values")
+	fs.StringVar(&opts.saveConfig, "save-config", "", "write the final answers to this YAML or JSON file")
+	fs.Var(&opts.vars, "var", "template variable as key=value (repeatable)")
+	fs.StringVar(&opts.onConflict, "on-conflict", "", "what to do with existing files: abort, skip, overwrite or new (default abort)")
+	fs.StringVar(&opts.postSteps, "post-generate", "", "comma separated steps to run after generation: tidy, fmt, build or the template's own, or 'none' (default none)")
+	fs.BoolVar(&opts.git, "git", false, "initialize a git repository with a .gitignore and an initial commit")
+	fs.StringVar(&opts.gitBranch, "git-branch", "", "initial branch of the git repository, implies --git (default git's init.defaultBranch)")
+	fs.StringVar(&opts.gitAuthor, "git-author", "", "author of the initial commit as \"Name <email>\", implies --git (default git's user.name and user.email)")
+	fs.StringVar(&opts.verify, "verify", "", "check the generated code: none, syntax (parse and gofmt), vet (syntax and go vet) or build (vet and go build) (default none)")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "print the files that would be generated without writing them")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitValidation
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(c.Stderr, "unexpected arguments: %v\n", fs.Args())
+		return ExitValidation
+	}
+
+	if opts.configFile != "" {
+		if err := opts.loadAnswers(); err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitValidation
+		}
+	}
+
+	// A single template directory decides the app type and package
+	if opts.template != "" {
+		if err := opts.useTemplate(); err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitValidation
+		}
+	}
+
+	// Validate the module path and policy here so the TUI never starts with a bad one
+	if opts.module != "" {
+		if err := generator.ValidateModulePath(opts.module); err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitValidation
+		}
+	}
+	if opts.onConflict != "" {
+		if _, err := generator.ParseConflictPolicy(opts.onConflict); err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitValidation
+		}
+	}
+	if opts.verify != "" {
+		if _, err := generator.ParseVerifyLevel(opts.verify); err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitValidation
+		}
+	}
+
+	// A branch or author asks for a repository
+	opts.git = opts.git || opts.gitBranch != "" || opts.gitAuthor != ""
+	if err := opts.gitOptions().Validate(); err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitValidation
+	}
+
+	// Everything required was given, so generate without a TTY.
+	// A dry run never needs to ask anything interactively.
+	if opts.complete() || opts.dryRun {
+		return c.generate(opts)
+	}
+
+	if !c.Interactive {
+		fmt.Fprintln(c.Stderr, "Error: --name, --type and --package are required when not running in a terminal")
+		return ExitValidation
+	}
+
+	return c.runInteractive(opts)
+}
+
+// loadAnswers reads the answers file and uses it for every value not set by a flag
+func (o *newOptions) loadAnswers() error {
+	answers, err := generator.LoadAnswers(o.configFile)
+	if err != nil {
+		return err
+	}
+	o.answers = answers
+
+	if o.name == "" {
+		o.name = answers.ProjectName
+	}
+	if o.module == "" {
+		o.module = answers.ModuleName
+	}
+	if o.appType == "" {
+		o.appType = answers.AppType
+	}
+	if o.packageName == "" {
+		o.packageName = answers.Package
+	}
+	if o.template == "" {
+		o.template = answers.Template
+	}
+	if o.dir == "" {
+		o.dir = answers.TargetDir
+		if o.dir == "" && answers.UseCurrentDir {
+			o.dir = "./"
+		}
+	}
+	if o.onConflict == "" {
+		o.onConflict = string(answers.OnConflict)
+	}
+	if o.postSteps == "" && answers.PostGenerate != nil {
+		o.postSteps = strings.Join(answers.PostGenerate, ",")
+		if o.postSteps == "" {
+			o.postSteps = generator.PostStepsNone
+		}
+	}
+	if o.verify == "" {
+		o.verify = string(answers.Verify)
+	}
+	o.git = o.git || answers.Git.Init
+	if o.gitBranch == "" {
+		o.gitBranch = answers.Git.Branch
+	}
+	if o.gitAuthor == "" {
+		o.gitAuthor = answers.Git.Author
+	}
+	o.git = o.git || o.gitBranch != "" || o.gitAuthor != ""
+
+	return nil
+}
+
+// useTemplate loads the --template directory and, when it holds a single
+// template, selects it unless --type or --package say otherwise
+func (o *newOptions) useTemplate() error {
+	templates, err := generator.LoadTemplateDir(o.template)
+	if err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		return fmt.Errorf("no templates found in %s", o.template)
+	}
+
+	if len(templates) == 1 {
+		if o.appType == "" {
+			o.appType = templates[0].AppType
+		}
+		if o.packageName == "" {
+			o.packageName = templates[0].Package
+		}
+	}
+	return nil
+}
+
+// complete reports whether all required values were supplied
+func (o newOptions) complete() bool {
+	return o.name != "" && o.appType != "" && o.packageName != ""
+}
+
+// config builds the project configuration from flags and answers
+func (o newOptions) config() (generator.ProjectConfig, error) {
+	config, err := generator.NewProjectConfig(o.name, o.appType, o.packageName, o.dir)
+	if err != nil {
+		return generator.ProjectConfig{}, err
+	}
+
+	if o.module != "" {
+		config.ModuleName = o.module
+	}
+	config.Vars = o.mergedVars()
+	config.Template = o.template
+
+	config.OnConflict = generator.ConflictPolicy(o.onConflict)
+	config.PostGenerate = o.postGenerate()
+	config.Git = o.gitOptions()
+	config.Verify = generator.VerifyLevel(o.verify)
+
+	return config, nil
+}
+
+// gitOptions returns the git repository settings from the flags
+func (o newOptions) gitOptions() generator.GitOptions {
+	return generator.GitOptions{Init: o.git, Branch: o.gitBranch, Author: o.gitAuthor}
+}
+
+// presetGit returns the git repository settings for the interactive prompts,
+// or nil to ask whether to create one
+func (o newOptions) presetGit() *generator.GitOptions {
+	if !o.git {
+		return nil
+	}
+	git := o.gitOptions()
+	return &git
+}
+
+// postGenerate returns the steps named by --post-generate, or nil when none
+// were named
+func (o newOptions) postGenerate() []string {
+	if o.postSteps == "" {
+		return nil
+	}
+	steps := []string{}
+	for _, name := range strings.Split(o.postSteps, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			steps = append(steps, name)
+		}
+	}
+	return steps
+}
+
+// mergedVars returns the answers file variables overridden by --var flags
+func (o newOptions) mergedVars() map[string]any {
+	if len(o.answers.Vars) == 0 && len(o.vars) == 0 {
+		return nil
+	}
+
+	vars := make(map[string]any, len(o.answers.Vars)+len(o.vars))
+	for name, value := range o.answers.Vars {
+		vars[name] = value
+	}
+	for name, value := range o.vars {
+		vars[name] = value
+	}
+	return vars
+}
+
+// generate builds the config from flags and runs the generator
+func (c *CLI) generate(opts newOptions) int {
+	config, err := opts.config()
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitValidation
+	}
+	if err := config.Validate(); err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitValidation
+	}
+
+	if opts.dryRun {
+		plan, err := generator.DryRun(config)
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitGenerationFailed
+		}
+		fmt.Fprintf(c.Stdout, "Dry run: would generate %s in %s\n\n", config.ProjectName, config.TargetDir)
+		fmt.Fprint(c.Stdout, plan.String())
+		if steps, err := generator.PostStepsFor(config); err == nil && len(steps) > 0 {
+			fmt.Fprintln(c.Stdout, "\nThen run:")
+			for _, step := range steps {
+				fmt.Fprintf(c.Stdout, "  %s\n", step.Run)
+			}
+		}
+		if config.Verify != "" && config.Verify != generator.VerifyNone {
+			fmt.Fprintf(c.Stdout, "\nThen verify the generated code (%s)\n", config.Verify)
+		}
+		if config.Git.Init {
+			if plan.Config().Git.Nested {
+				fmt.Fprintln(c.Stdout, "\nNo git repository: the project is inside a git work tree")
+			} else {
+				fmt.Fprintln(c.Stdout, "\nThen initialize a git repository with an initial commit")
+			}
+		}
+		if conflicts := plan.Conflicts(config.TargetDir); len(conflicts) > 0 {
+			policy := config.OnConflict
+			if policy == "" {
+				policy = generator.ConflictAbort
+			}
+			fmt.Fprintf(c.Stdout, "\nExisting files (on conflict: %s):\n", policy)
+			for _, path := range conflicts {
+				fmt.Fprintf(c.Stdout, "  %s\n", path)
+			}
+		}
+		return ExitOK
+	}
+
+	plan, err := generator.Generate(config)
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		var conflictErr *generator.ConflictError
+		if errors.As(err, &conflictErr) {
+			fmt.Fprintln(c.Stderr, "Use --on-conflict skip, overwrite or new to generate anyway")
+		}
+		return ExitGenerationFailed
+	}
+	for _, warning := range plan.FormatErrors() {
+		fmt.Fprintf(c.Stderr, "Warning: %s\n", warning)
+	}
+
+	if code := c.saveAnswers(opts, config); code != ExitOK {
+		return code
+	}
+
+	fmt.Fprintf(c.Stdout, "Project %s created in %s\n", config.ProjectName, config.TargetDir)
+	if code := c.runPostSteps(config); code != ExitOK {
+		return code
+	}
+	if code := c.verify(config); code != ExitOK {
+		return code
+	}
+	if code := c.initGit(config); code != ExitOK {
+		return code
+	}
+	c.printNextSteps(config)
+	return ExitOK
+}
+
+// runPostSteps runs the post-generation steps in the new project, streaming
+// their output, and stops at the first one that fails
+func (c *CLI) runPostSteps(config generator.ProjectConfig) int {
+	steps, err := generator.PostStepsFor(config)
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+
+	for _, step := range steps {
+		cmd, err := step.Command(config.TargetDir)
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitGenerationFailed
+		}
+		cmd.Stdout, cmd.Stderr = c.Stdout, c.Stderr
+
+		fmt.Fprintf(c.Stdout, "Running %s\n", cmd)
+		if err := generator.RunPostStep(context.Background(), cmd); err != nil {
+			// The output was streamed already
+			fmt.Fprintf(c.Stderr, "Error: post-generation step %s failed: %v\n", step.Name, errors.Unwrap(err))
+			return ExitGenerationFailed
+		}
+	}
+	return ExitOK
+}
+
+// verify checks the generated code and reports every problem by file and line
+func (c *CLI) verify(config generator.ProjectConfig) int {
+	if config.Verify == "" || config.Verify == generator.VerifyNone {
+		return ExitOK
+	}
+
+	fmt.Fprintf(c.Stdout, "Verifying generated code (%s)\n", config.Verify)
+	err := generator.Verify(context.Background(), config, c.Stdout)
+	var verifyErr *generator.VerifyError
+	switch {
+	case errors.As(err, &verifyErr):
+		for _, issue := range verifyErr.Issues {
+			fmt.Fprintln(c.Stderr, issue)
+		}
+		fmt.Fprintf(c.Stderr, "Error: generated code has %d problem(s)\n", len(verifyErr.Issues))
+		return ExitGenerationFailed
+	case err != nil:
+		fmt.Fprintf(c.Stderr, "Error: failed to verify generated code: %v\n", err)
+		return ExitGenerationFailed
+	}
+	return ExitOK
+}
+
+// initGit creates the git repository of the new project, if one was requested
+func (c *CLI) initGit(config generator.ProjectConfig) int {
+	if !config.Git.Init {
+		return ExitOK
+	}
+
+	if _, err := generator.InitGit(context.Background(), config.TargetDir, config.Git, c.Stdout); err != nil {
+		fmt.Fprintf(c.Stderr, "Error: failed to initialize git repository: %v\n", err)
+		return ExitGenerationFailed
+	}
+	return ExitOK
+}
+
+// printNextSteps prints the commands the template suggests after generation
+func (c *CLI) printNextSteps(config generator.ProjectConfig) {
+	tmpl, err := generator.FindTemplateIn(config.Template, config.AppType, config.Package)
+	if err != nil {
+		return
+	}
+	steps, err := tmpl.RenderNextSteps(config)
+	if err != nil || len(steps) == 0 {
+		return
+	}
+
+	fmt.Fprintln(c.Stdout, "\nNext steps:")
+	if !config.UseCurrentDir {
+		fmt.Fprintf(c.Stdout, "  cd %s\n", config.TargetDir)
+	}
+	for _, step := range steps {
+		fmt.Fprintf(c.Stdout, "  %s\n", step)
+	}
+}
+
+// saveAnswers writes config to the --save-config file, if one was requested
+func (c *CLI) saveAnswers(opts newOptions, config generator.ProjectConfig) int {
+	if opts.saveConfig == "" {
+		return ExitOK
+	}
+
+	if err := generator.SaveAnswers(opts.saveConfig, config); err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+
+	return ExitOK
+}
+
+// runInteractive starts the TUI with the supplied values filled in
+func (c *CLI) runInteractive(opts newOptions) int {
+	// Reject bad flag values before asking for the rest
+	if opts.name != "" {
+		if err := generator.ValidateProjectName(opts.name); err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitValidation
+		}
+	}
+
+	p := prompts.NewProgramWithOptions(prompts.Options{
+		ProjectName: opts.name,
+		AppType:     opts.appType,
+		Package:     opts.packageName,
+		Template:    opts.template,
+		TargetDir:   opts.dir,
+		ModuleName:  opts.module,
+		Vars:        opts.mergedVars(),
+		OnConflict:  generator.ConflictPolicy(opts.onConflict),
+		PostSteps:   opts.postGenerate(),
+		Git:         opts.presetGit(),
+		Verify:      generator.VerifyLevel(opts.verify),
+	})
+
+	// Run the program and get the result
+	model, err := p.Run()
+	if err != nil {
+		if errors.Is(err, tea.ErrInterrupted) {
+			fmt.Fprintln(c.Stdout, "\nOperation cancelled by user")
+			return ExitCancelled
+		}
+		fmt.Fprintf(c.Stderr, "Error running program: %v\n", err)
+		return ExitGenerationFailed
+	}
+
+	// Type assert to get our model
+	m, ok := model.(prompts.Model)
+	if !ok {
+		fmt.Fprintln(c.Stderr, "Unexpected model type")
+		return ExitGenerationFailed
+	}
+
+	// Check if user completed the process successfully
+	switch {
+	case m.GenerationSuccess():
+		config, err := m.Config()
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitGenerationFailed
+		}
+		if code := c.saveAnswers(opts, config); code != ExitOK {
+			return code
+		}
+		if m.Cancelled() {
+			fmt.Fprintln(c.Stdout, "Project generated; post-generation steps cancelled")
+			return ExitCancelled
+		}
+		if err := m.PostStepError(); err != nil {
+			fmt.Fprintf(c.Stderr, "Project generated, but a step after generation failed: %v\n", err)
+			return ExitGenerationFailed
+		}
+		fmt.Fprintln(c.Stdout, "Project generation completed successfully!")
+		return ExitOK
+	case m.GenerationError() != nil:
+		fmt.Fprintf(c.Stderr, "Project generation failed: %v\n", m.GenerationError())
+		return ExitGenerationFailed
+	default:
+		fmt.Fprintln(c.Stdout, "Project generation cancelled")
+		return ExitCancelled
+	}
+}
+
+// varsFlag collects repeated key=value flags
+type varsFlag map[string]string
+
+func (v *varsFlag) String() string {
+	return fmt.Sprint(map[string]string(*v))
+}
+
+func (v *varsFlag) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	if *v == nil {
+		*v = varsFlag{}
+	}
+	(*v)[name] = val
+	return nil
+}
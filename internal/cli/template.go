@@ -0,0 +1,445 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manuelbamise/go-ten/internal/cache"
+	"github.com/manuelbamise/go-ten/internal/generator"
+	"github.com/manuelbamise/go-ten/internal/templatetest"
+)
+
+// runTemplate implements "go-ten template <command>"
+func (c *CLI) runTemplate(args []string) int {
+	if len(args) == 0 {
+		c.templateUsage(c.Stderr)
+		return ExitValidation
+	}
+
+	switch args[0] {
+	case "add":
+		return c.runTemplateAdd(args[1:])
+	case "list":
+		return c.runTemplateList(args[1:])
+	case "update":
+		return c.runTemplateUpdate(args[1:])
+	case "prune":
+		return c.runTemplatePrune(args[1:])
+	case "info":
+		return c.runTemplateInfo(args[1:])
+	case "lint":
+		return c.runTemplateLint(args[1:])
+	case "test":
+		return c.runTemplateTest(args[1:])
+	case "help", "-h", "--help":
+		c.templateUsage(c.Stdout)
+		return ExitOK
+	default:
+		fmt.Fprintf(c.Stderr, "unknown template command %q\n\n", args[0])
+		c.templateUsage(c.Stderr)
+		return ExitValidation
+	}
+}
+
+// templateUsage prints the help of the template command
+func (c *CLI) templateUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: go-ten template <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Manage the user template cache and check templates.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  add <path-or-archive>   Add the templates of a directory, archive or git+<url>@<ref>")
+	fmt.Fprintln(w, "  list                    List cached templates and git mirrors")
+	fmt.Fprintln(w, "  update [id...]          Fetch cached templates again from their source")
+	fmt.Fprintln(w, "  prune                   Remove templates and git mirrors that were not used recently")
+	fmt.Fprintln(w, "  info <id>               Show details of a cached template")
+	fmt.Fprintln(w, "  lint [dir...]           Check templates for errors before they are used")
+	fmt.Fprintln(w, "  test --golden <dir> [dir...]")
+	fmt.Fprintln(w, "                          Compare the output of templates with golden directories")
+}
+
+// parseTemplateFlags parses the flags of a template command and checks the
+// number of positional arguments
+func (c *CLI) parseTemplateFlags(fs *flag.FlagSet, args []string, minArgs, maxArgs int) (bool, int) {
+	fs.SetOutput(c.Stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return false, ExitOK
+		}
+		return false, ExitValidation
+	}
+	if fs.NArg() < minArgs || maxArgs >= 0 && fs.NArg() > maxArgs {
+		fmt.Fprintf(c.Stderr, "Error: wrong number of arguments\n")
+		fs.Usage()
+		return false, ExitValidation
+	}
+	return true, ExitOK
+}
+
+// runTemplateAdd implements "go-ten template add"
+func (c *CLI) runTemplateAdd(args []string) int {
+	fs := flag.NewFlagSet("template add", flag.ContinueOnError)
+	if ok, code := c.parseTemplateFlags(fs, args, 1, 1); !ok {
+		return code
+	}
+
+	entries, err := generator.CacheTemplates(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(c.Stdout, "Added %s %s (%s)\n", entry.ID, entry.Version, entry.Digest)
+	}
+	return ExitOK
+}
+
+// runTemplateList implements "go-ten template list"
+func (c *CLI) runTemplateList(args []string) int {
+	fs := flag.NewFlagSet("template list", flag.ContinueOnError)
+	if ok, code := c.parseTemplateFlags(fs, args, 0, 0); !ok {
+		return code
+	}
+
+	index, err := cache.Open()
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+	mirrors, err := cache.Mirrors()
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+
+	if len(index.Templates) == 0 {
+		fmt.Fprintln(c.Stdout, "No cached templates. Add one with 'go-ten template add <path-or-archive>'.")
+	} else {
+		w := tabwriter.NewWriter(c.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tVERSION\tDIGEST\tLAST USED\tSOURCE")
+		for _, entry := range index.Templates {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.ID, orDash(entry.Version), shortDigest(entry.Digest), formatTime(entry.LastUsed), entry.Source)
+		}
+		w.Flush()
+	}
+
+	// Mirrors of git sources, kept to fetch again quickly and offline
+	if len(mirrors) > 0 {
+		fmt.Fprintln(c.Stdout)
+		w := tabwriter.NewWriter(c.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "GIT MIRROR\tLAST USED\tDIRECTORY")
+		for _, mirror := range mirrors {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", orDash(mirror.URL), formatTime(mirror.LastUsed), mirror.Dir)
+		}
+		w.Flush()
+	}
+	return ExitOK
+}
+
+// runTemplateUpdate implements "go-ten template update"
+func (c *CLI) runTemplateUpdate(args []string) int {
+	fs := flag.NewFlagSet("template update", flag.ContinueOnError)
+	if ok, code := c.parseTemplateFlags(fs, args, 0, -1); !ok {
+		return code
+	}
+
+	index, err := cache.Open()
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+
+	// Update the named templates, or all of them
+	entries := index.Templates
+	if fs.NArg() > 0 {
+		entries = nil
+		for _, id := range fs.Args() {
+			entry, ok := index.Get(id)
+			if !ok {
+				fmt.Fprintf(c.Stderr, "Error: template %s is not in the cache\n", id)
+				return ExitValidation
+			}
+			entries = append(entries, *entry)
+		}
+	}
+
+	// A source can hold several templates, so fetch each source once
+	previous := map[string]string{}
+	var sources []string
+	for _, entry := range entries {
+		if _, ok := previous[entry.ID]; !ok {
+			previous[entry.ID] = entry.Digest
+		}
+		if !contains(sources, entry.Source) {
+			sources = append(sources, entry.Source)
+		}
+	}
+
+	code := ExitOK
+	for _, source := range sources {
+		updated, err := generator.CacheTemplates(source)
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "Error: failed to update from %s: %v\n", source, err)
+			code = ExitGenerationFailed
+			continue
+		}
+		for _, entry := range updated {
+			if old, ok := previous[entry.ID]; ok && old == entry.Digest {
+				fmt.Fprintf(c.Stdout, "%s is up to date (%s)\n", entry.ID, shortDigest(entry.Digest))
+			} else {
+				fmt.Fprintf(c.Stdout, "Updated %s %s (%s)\n", entry.ID, entry.Version, entry.Digest)
+			}
+		}
+	}
+	return code
+}
+
+// runTemplatePrune implements "go-ten template prune"
+func (c *CLI) runTemplatePrune(args []string) int {
+	fs := flag.NewFlagSet("template prune", flag.ContinueOnError)
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "remove templates and git mirrors not used for this long")
+	all := fs.Bool("all", false, "remove every cached template and git mirror")
+	if ok, code := c.parseTemplateFlags(fs, args, 0, 0); !ok {
+		return code
+	}
+
+	index, err := cache.Open()
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+
+	before := time.Now().Add(-*olderThan)
+	if *all {
+		before = time.Now().Add(time.Hour)
+	}
+	removed, mirrors, err := index.Prune(before)
+	for _, entry := range removed {
+		fmt.Fprintf(c.Stdout, "Removed %s (last used %s)\n", entry.ID, formatTime(entry.LastUsed))
+	}
+	for _, mirror := range mirrors {
+		fmt.Fprintf(c.Stdout, "Removed git mirror %s (last used %s)\n", orDash(mirror.URL), formatTime(mirror.LastUsed))
+	}
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+	if len(removed) == 0 && len(mirrors) == 0 {
+		fmt.Fprintln(c.Stdout, "Nothing to prune")
+	}
+	return ExitOK
+}
+
+// runTemplateInfo implements "go-ten template info"
+func (c *CLI) runTemplateInfo(args []string) int {
+	fs := flag.NewFlagSet("template info", flag.ContinueOnError)
+	if ok, code := c.parseTemplateFlags(fs, args, 1, 1); !ok {
+		return code
+	}
+
+	index, err := cache.Open()
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+	entry, ok := index.Get(fs.Arg(0))
+	if !ok {
+		fmt.Fprintf(c.Stderr, "Error: template %s is not in the cache\n", fs.Arg(0))
+		return ExitValidation
+	}
+
+	w := tabwriter.NewWriter(c.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", entry.ID)
+	fmt.Fprintf(w, "Name:\t%s\n", entry.Name)
+	fmt.Fprintf(w, "Version:\t%s\n", orDash(entry.Version))
+	fmt.Fprintf(w, "Source:\t%s\n", entry.Source)
+	if entry.Commit != "" {
+		fmt.Fprintf(w, "Commit:\t%s\n", entry.Commit)
+	}
+	fmt.Fprintf(w, "Digest:\t%s\n", entry.Digest)
+	fmt.Fprintf(w, "Added:\t%s\n", formatTime(entry.Added))
+	fmt.Fprintf(w, "Last used:\t%s\n", formatTime(entry.LastUsed))
+	fmt.Fprintf(w, "Path:\t%s\n", index.Path(entry.ID))
+
+	// Check the files, as generation would
+	integrity := "ok"
+	if err := index.Verify(entry.ID); err != nil {
+		integrity = err.Error()
+	}
+	fmt.Fprintf(w, "Integrity:\t%s\n", integrity)
+	w.Flush()
+
+	// Show what the template asks for
+	templates, err := generator.LoadTemplateDir(index.Path(entry.ID))
+	if err == nil && len(templates) == 1 {
+		tmpl := templates[0]
+		if tmpl.Description != "" {
+			fmt.Fprintf(c.Stdout, "\n%s\n", tmpl.Description)
+		}
+		if len(tmpl.Variables) > 0 {
+			fmt.Fprintln(c.Stdout, "\nVariables:")
+			for _, v := range tmpl.Variables {
+				fmt.Fprintf(c.Stdout, "  %s (%s, default %v)\n", v.Name, v.Type, v.Default)
+			}
+		}
+	}
+
+	if integrity != "ok" {
+		return ExitGenerationFailed
+	}
+	return ExitOK
+}
+
+// runTemplateLint implements "go-ten template lint"
+func (c *CLI) runTemplateLint(args []string) int {
+	fs := flag.NewFlagSet("template lint", flag.ContinueOnError)
+	if ok, code := c.parseTemplateFlags(fs, args, 0, -1); !ok {
+		return code
+	}
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	problems := 0
+	for _, dir := range dirs {
+		templates, err := generator.LoadTemplateDir(dir)
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitValidation
+		}
+		if len(templates) == 0 {
+			fmt.Fprintf(c.Stderr, "Error: no templates found in %s\n", dir)
+			return ExitValidation
+		}
+
+		for i := range templates {
+			tmpl := &templates[i]
+			issues, err := generator.Lint(tmpl)
+			if err != nil {
+				fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+				return ExitGenerationFailed
+			}
+			if len(issues) == 0 {
+				fmt.Fprintf(c.Stdout, "%s: ok\n", tmpl.ID())
+				continue
+			}
+			for _, issue := range issues {
+				// Issues name template files; print them relative to the working directory, like go vet
+				issue.File = filepath.ToSlash(filepath.Join(tmpl.Path, issue.File))
+				fmt.Fprintln(c.Stdout, issue)
+			}
+			problems += len(issues)
+		}
+	}
+
+	if problems > 0 {
+		fmt.Fprintf(c.Stderr, "Found %d problem(s)\n", problems)
+		return ExitGenerationFailed
+	}
+	return ExitOK
+}
+
+// runTemplateTest implements "go-ten template test"
+func (c *CLI) runTemplateTest(args []string) int {
+	fs := flag.NewFlagSet("template test", flag.ContinueOnError)
+	golden := fs.String("golden", "", "directory with the answers and golden output of every template, outside the template directory")
+	update := fs.Bool("update", false, "rewrite the golden directories with the current output")
+	if ok, code := c.parseTemplateFlags(fs, args, 0, -1); !ok {
+		return code
+	}
+	if *golden == "" {
+		fmt.Fprintf(c.Stderr, "Error: --golden is required\n")
+		fs.Usage()
+		return ExitValidation
+	}
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	failed := 0
+	for _, dir := range dirs {
+		templates, err := generator.LoadTemplateDir(dir)
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+			return ExitValidation
+		}
+		if len(templates) == 0 {
+			fmt.Fprintf(c.Stderr, "Error: no templates found in %s\n", dir)
+			return ExitValidation
+		}
+
+		for _, tmpl := range templates {
+			// Every template has its own cases below the golden directory
+			suite := templatetest.Suite{
+				Template:  dir,
+				AppType:   tmpl.AppType,
+				Package:   tmpl.Package,
+				GoldenDir: filepath.Join(*golden, tmpl.ID()),
+			}
+			results, err := suite.Run(*update)
+			if err != nil {
+				fmt.Fprintf(c.Stderr, "Error: %s: %v\n", tmpl.ID(), err)
+				return ExitGenerationFailed
+			}
+
+			for _, result := range results {
+				switch {
+				case result.Updated:
+					fmt.Fprintf(c.Stdout, "%s/%s: updated\n", tmpl.ID(), result.Case)
+				case result.OK():
+					fmt.Fprintf(c.Stdout, "%s/%s: ok\n", tmpl.ID(), result.Case)
+				default:
+					fmt.Fprintf(c.Stdout, "%s/%s: FAIL\n%s", tmpl.ID(), result.Case, result)
+					failed++
+				}
+			}
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(c.Stderr, "%d case(s) differ from their golden directory, rerun with -update to accept the output\n", failed)
+		return ExitGenerationFailed
+	}
+	return ExitOK
+}
+
+// shortDigest abbreviates a digest for tables
+func shortDigest(digest string) string {
+	if len(digest) > 15 {
+		return digest[:15]
+	}
+	return digest
+}
+
+// formatTime formats a cache timestamp in local time
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+func TestRunTemplateCommands(t *testing.T) {
+	t.Setenv(generator.TemplatePathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	src := t.TempDir()
+	manifest := "name: Worker\nappType: worker\npackage: stdlib\nversion: 1.2.0\n"
+	if err := os.WriteFile(filepath.Join(src, generator.ManifestFile), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, stdout, stderr := newTestCLI()
+	if code := c.Run([]string{"template", "add", src}); code != ExitOK {
+		t.Fatalf("template add: exit code %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Added worker-stdlib 1.2.0") {
+		t.Errorf("Unexpected add output: %s", stdout.String())
+	}
+
+	c, stdout, _ = newTestCLI()
+	if code := c.Run([]string{"template", "list"}); code != ExitOK {
+		t.Fatalf("template list: exit code %d", code)
+	}
+	for _, want := range []string{"worker-stdlib", "1.2.0", "h1:", "never", src} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("template list should show %q:\n%s", want, stdout.String())
+		}
+	}
+
+	c, stdout, _ = newTestCLI()
+	if code := c.Run([]string{"template", "info", "worker-stdlib"}); code != ExitOK {
+		t.Fatalf("template info: exit code %d", code)
+	}
+	if !strings.Contains(stdout.String(), "Integrity:  ok") {
+		t.Errorf("template info should report the integrity check:\n%s", stdout.String())
+	}
+
+	// Updating picks up changes of the source
+	if err := os.WriteFile(filepath.Join(src, "README.md"), []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, stdout, _ = newTestCLI()
+	if code := c.Run([]string{"template", "update"}); code != ExitOK || !strings.Contains(stdout.String(), "Updated worker-stdlib") {
+		t.Errorf("template update: exit code %d:\n%s", code, stdout.String())
+	}
+
+	c, stdout, _ = newTestCLI()
+	if code := c.Run([]string{"template", "prune", "--all"}); code != ExitOK || !strings.Contains(stdout.String(), "Removed worker-stdlib") {
+		t.Errorf("template prune: exit code %d:\n%s", code, stdout.String())
+	}
+
+	for _, args := range [][]string{{"template"}, {"template", "bogus"}, {"template", "info"}, {"template", "info", "missing-id"}} {
+		c, _, _ = newTestCLI()
+		if code := c.Run(args); code != ExitValidation {
+			t.Errorf("Expected exit code %d for %v, got %d", ExitValidation, args, code)
+		}
+	}
+}
+
+func TestRunTemplateLint(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		generator.ManifestFile: "name: Worker\nappType: worker\npackage: stdlib\n",
+		"main.go.tmpl":         "package main\n\nfunc main() {\n\tprintln({{quote .ProjectName}})\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, stdout, stderr := newTestCLI()
+	if code := c.Run([]string{"template", "lint", dir}); code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s%s", ExitOK, code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "worker-stdlib: ok") {
+		t.Errorf("Unexpected output: %s", stdout.String())
+	}
+
+	// Problems are reported by file, line and column
+	if err := os.WriteFile(filepath.Join(dir, "main.go.tmpl"), []byte("package main\n\nfunc main() {\n\tprintln({{quote .Project}})\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, stdout, _ = newTestCLI()
+	if code := c.Run([]string{"template", "lint", dir}); code != ExitGenerationFailed {
+		t.Errorf("Expected exit code %d, got %d", ExitGenerationFailed, code)
+	}
+	want := filepath.ToSlash(filepath.Join(dir, "main.go.tmpl")) + ":4:18: ProjectConfig has no field Project"
+	if !strings.Contains(stdout.String(), want) {
+		t.Errorf("Expected %q in:\n%s", want, stdout.String())
+	}
+}
+
+func TestRunTemplateTest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		generator.ManifestFile: "name: Worker\nappType: worker\npackage: stdlib\n",
+		"main.go.tmpl":         "package main\n\nfunc main() {\n\tprintln({{quote .ProjectName}})\n}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden := t.TempDir()
+
+	// The golden directory is written with -update
+	c, stdout, stderr := newTestCLI()
+	if code := c.Run([]string{"template", "test", "--golden", golden, "-update", dir}); code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s%s", ExitOK, code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "worker-stdlib/default: updated") {
+		t.Errorf("Unexpected output: %s", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(golden, "worker-stdlib", "default", "main.go")); err != nil {
+		t.Fatalf("Expected the golden main.go: %v", err)
+	}
+
+	c, stdout, stderr = newTestCLI()
+	if code := c.Run([]string{"template", "test", "--golden", golden, dir}); code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s%s", ExitOK, code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "worker-stdlib/default: ok") {
+		t.Errorf("Unexpected output: %s", stdout.String())
+	}
+
+	// A changed template fails with a diff
+	if err := os.WriteFile(filepath.Join(dir, "main.go.tmpl"), []byte("package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, stdout, _ = newTestCLI()
+	if code := c.Run([]string{"template", "test", "--golden", golden, dir}); code != ExitGenerationFailed {
+		t.Errorf("Expected exit code %d, got %d", ExitGenerationFailed, code)
+	}
+	for _, want := range []string{"worker-stdlib/default: FAIL", "main.go: changed", "+\tprintln(\"hi\")"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, stdout.String())
+		}
+	}
+
+	// The golden directory is required
+	c, _, _ = newTestCLI()
+	if code := c.Run([]string{"template", "test", dir}); code != ExitValidation {
+		t.Errorf("Expected exit code %d, got %d", ExitValidation, code)
+	}
+}
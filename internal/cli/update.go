@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+// runUpdate implements "go-ten update"
+func (c *CLI) runUpdate(args []string) int {
+	var opts generator.UpdateOptions
+
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	fs.StringVar(&opts.Dir, "dir", ".", "project directory containing "+generator.LockFile)
+	fs.StringVar(&opts.Template, "template", "", "template source to update to (default the one in "+generator.LockFile+")")
+	fs.StringVar(&opts.From, "from", "", "template source of the version the project was generated from (default from "+generator.LockFile+")")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "report what would change without writing")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitValidation
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(c.Stderr, "unexpected arguments: %v\n", fs.Args())
+		return ExitValidation
+	}
+
+	result, err := generator.Update(opts)
+	if err != nil {
+		fmt.Fprintf(c.Stderr, "Error: %v\n", err)
+		return ExitGenerationFailed
+	}
+
+	c.printUpdate(result, opts.DryRun)
+
+	if conflicts := result.Conflicts(); conflicts > 0 {
+		if opts.DryRun {
+			fmt.Fprintf(c.Stderr, "\n%d conflict(s) would need to be resolved.\n", conflicts)
+		} else {
+			fmt.Fprintf(c.Stderr, "\n%d conflict(s) left. Resolve the conflict markers, then commit.\n", conflicts)
+		}
+		return ExitGenerationFailed
+	}
+	return ExitOK
+}
+
+// printUpdate prints the changed files and a summary of an update
+func (c *CLI) printUpdate(result *generator.UpdateResult, dryRun bool) {
+	if dryRun {
+		fmt.Fprint(c.Stdout, "Dry run: ")
+	}
+	fmt.Fprintf(c.Stdout, "Updating %s %s -> %s\n", result.To.ID, orDash(templateVersion(result.From)), orDash(templateVersion(result.To)))
+
+	counts := map[generator.UpdateStatus]int{}
+	listed := false
+	for _, f := range result.Files {
+		counts[f.Status]++
+		if f.Status == generator.UpdateUnchanged {
+			continue
+		}
+
+		if !listed {
+			fmt.Fprintln(c.Stdout)
+			listed = true
+		}
+		line := fmt.Sprintf("  %-9s %s", f.Status, f.Path)
+		if f.Conflicts > 1 {
+			line += fmt.Sprintf(" (%d conflicts)", f.Conflicts)
+		}
+		if f.Note != "" {
+			line += " (" + f.Note + ")"
+		}
+		fmt.Fprintln(c.Stdout, line)
+	}
+
+	fmt.Fprintf(c.Stdout, "\n%d updated, %d merged, %d conflicting, %d added, %d removed, %d kept, %d unchanged\n",
+		counts[generator.UpdateUpdated], counts[generator.UpdateMerged], counts[generator.UpdateConflict],
+		counts[generator.UpdateAdded], counts[generator.UpdateRemoved], counts[generator.UpdateKept],
+		counts[generator.UpdateUnchanged])
+}
+
+// templateVersion describes a template version for reports: its version,
+// commit or digest, whichever is known
+func templateVersion(t generator.LockTemplate) string {
+	switch {
+	case t.Version != "":
+		return t.Version
+	case len(t.Commit) >= 12:
+		return t.Commit[:12]
+	case t.Digest != "":
+		return shortDigest(t.Digest)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+func TestRunUpdate(t *testing.T) {
+	t.Setenv(generator.TemplatePathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	writeVersion := func(dir, version, readme string) {
+		manifest := "name: Worker\nappType: worker\npackage: stdlib\nversion: " + version + "\n"
+		if err := os.WriteFile(filepath.Join(dir, generator.ManifestFile), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(readme), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v1, v2 := t.TempDir(), t.TempDir()
+	writeVersion(v1, "1.0.0", "# Worker\n")
+	writeVersion(v2, "2.0.0", "# Worker v2\n")
+
+	c, _, stderr := newTestCLI()
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	if code := c.Run([]string{"new", "--name", "my-worker", "--template", v1, "--dir", targetDir}); code != ExitOK {
+		t.Fatalf("new: exit code %d: %s", code, stderr.String())
+	}
+
+	// An unmodified file updates cleanly
+	c, stdout, stderr := newTestCLI()
+	if code := c.Run([]string{"update", "--dir", targetDir, "--template", v2, "--from", v1}); code != ExitOK {
+		t.Fatalf("update: exit code %d: %s", code, stderr.String())
+	}
+	for _, want := range []string{"Updating worker-stdlib 1.0.0 -> 2.0.0", "updated   README.md", "1 updated"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("update output should contain %q:\n%s", want, stdout.String())
+		}
+	}
+
+	// Conflicting local changes are reported with a failing exit code
+	if err := os.WriteFile(filepath.Join(targetDir, "README.md"), []byte("# Mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeVersion(v1, "3.0.0", "# Worker v3\n")
+	c, stdout, stderr = newTestCLI()
+	if code := c.Run([]string{"update", "--dir", targetDir, "--template", v1, "--from", v2}); code != ExitGenerationFailed {
+		t.Fatalf("update with a conflict: exit code %d, want %d", code, ExitGenerationFailed)
+	}
+	if !strings.Contains(stdout.String(), "conflict  README.md") || !strings.Contains(stderr.String(), "1 conflict(s) left") {
+		t.Errorf("update should report the conflict:\n%s%s", stdout.String(), stderr.String())
+	}
+
+	// A directory without a lockfile cannot be updated
+	c, _, _ = newTestCLI()
+	if code := c.Run([]string{"update", "--dir", t.TempDir()}); code != ExitGenerationFailed {
+		t.Errorf("update without a lockfile: exit code %d, want %d", code, ExitGenerationFailed)
+	}
+}
@@ -1,6 +1,183 @@
+// Package commands runs external commands for go-ten, such as the steps
+// that follow project generation
 package commands
 
-// Reserved for future shell command execution
-// This package will contain shell command execution utilities
-// when the project scaffolding system needs to execute
-// system commands as part of the project generation process.
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// waitDelay is how long Run waits for the output of a killed command, e.g.
+// when a child process it started still holds it open
+const waitDelay = 2 * time.Second
+
+// Command is an external command and how to run it
+type Command struct {
+	Name    string        // program, looked up in PATH
+	Args    []string      // arguments after the program
+	Dir     string        // working directory; the current one when empty
+	Env     []string      // "KEY=value" pairs added to the environment of go-ten
+	Timeout time.Duration // kill the command after this long; no limit when zero
+
+	// Stdout and Stderr receive the output while the command runs. The
+	// output is captured in the Result either way.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Result is the outcome of a command that ran
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int // -1 when the command was killed
+	Duration time.Duration
+}
+
+// Error reports a command that could not be started, failed, timed out or
+// was cancelled
+type Error struct {
+	Command string
+	Result  *Result // nil when the command did not start
+	Err     error
+}
+
+// Error describes the failure, with the last lines of the error output
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.Command, e.Err)
+	if e.Result != nil {
+		if tail := lastLines(e.Result.Stderr, 10); tail != "" {
+			msg += "\n" + tail
+		}
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error, e.g. context.DeadlineExceeded
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// String returns the command line, e.g. "go mod tidy"
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Run runs cmd and waits for it. It fails with an *Error when the command
+// exits with a non-zero status, exceeds its timeout or ctx is cancelled;
+// the command is killed in the last two cases.
+func Run(ctx context.Context, cmd Command) (*Result, error) {
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.Timeout)
+		defer cancel()
+	}
+
+	c := exec.CommandContext(ctx, cmd.Name, cmd.Args...)
+	c.Dir = cmd.Dir
+	c.WaitDelay = waitDelay
+	if len(cmd.Env) > 0 {
+		c.Env = append(os.Environ(), cmd.Env...)
+	}
+
+	// Capture the output, copying it to the writers as it arrives
+	var stdout, stderr bytes.Buffer
+	c.Stdout = output(&stdout, cmd.Stdout)
+	c.Stderr = output(&stderr, cmd.Stderr)
+
+	start := time.Now()
+	err := c.Run()
+	result := &Result{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: c.ProcessState.ExitCode(),
+		Duration: time.Since(start),
+	}
+
+	switch {
+	case err == nil:
+		return result, nil
+	case c.ProcessState == nil:
+		// The command never started
+		return nil, &Error{Command: cmd.String(), Err: err}
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) && cmd.Timeout > 0:
+		return result, &Error{Command: cmd.String(), Result: result, Err: fmt.Errorf("timed out after %s: %w", cmd.Timeout, ctx.Err())}
+	case ctx.Err() != nil:
+		return result, &Error{Command: cmd.String(), Result: result, Err: ctx.Err()}
+	default:
+		return result, &Error{Command: cmd.String(), Result: result, Err: err}
+	}
+}
+
+// output returns the writer for one output stream
+func output(buf *bytes.Buffer, w io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, w)
+}
+
+// lastLines returns the last n lines of s without the trailing newline
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Split splits a command line into the program and its arguments. Words are
+// separated by spaces; single and double quotes group words and a backslash
+// escapes the next character outside single quotes. There is no expansion of
+// variables or globs.
+func Split(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'' && r == '\'', quote == '"' && r == '"':
+			quote = 0
+		case quote == '\'':
+			word.WriteRune(r)
+		case r == '\\':
+			if i+1 == len(runes) {
+				return nil, fmt.Errorf("trailing backslash in %q", line)
+			}
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case quote == '"':
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// Output is captured and streamed, in the working directory and environment given
+	dir := t.TempDir()
+	var streamed bytes.Buffer
+	result, err := Run(context.Background(), Command{
+		Name:   "sh",
+		Args:   []string{"-c", `pwd; echo "$GREETING"; echo oops >&2`},
+		Dir:    dir,
+		Env:    []string{"GREETING=hello"},
+		Stdout: &streamed,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(strings.Split(result.Stdout, "\n")[0]), strings.TrimPrefix(dir, "/private")) ||
+		!strings.Contains(result.Stdout, "hello\n") || result.Stderr != "oops\n" || result.ExitCode != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if streamed.String() != result.Stdout {
+		t.Errorf("Streamed output %q differs from captured %q", streamed.String(), result.Stdout)
+	}
+
+	// A failing command reports its exit code and error output
+	result, err = Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "echo broken >&2; exit 3"}})
+	var cmdErr *Error
+	if !errors.As(err, &cmdErr) || result.ExitCode != 3 || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected an *Error with exit code 3, got %v (%+v)", err, result)
+	}
+
+	// A command that cannot start has no result
+	if result, err := Run(context.Background(), Command{Name: "go-ten-no-such-command"}); err == nil || result != nil {
+		t.Errorf("Expected a start error, got %+v, %v", result, err)
+	}
+}
+
+func TestRunTimeoutAndCancel(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	start := time.Now()
+	_, err := Run(context.Background(), Command{Name: "sleep", Args: []string{"10"}, Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err := Run(ctx, Command{Name: "sleep", Args: []string{"10"}}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancellation, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Killed commands took %s", elapsed)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := map[string][]string{
+		"go mod tidy":                    {"go", "mod", "tidy"},
+		"  gofmt   -w  . ":               {"gofmt", "-w", "."},
+		`git commit -m "initial commit"`: {"git", "commit", "-m", "initial commit"},
+		`echo 'a "b"' c\ d`:              {"echo", `a "b"`, "c d"},
+		`echo ""`:                        {"echo", ""},
+		"":                               nil,
+	}
+	for line, want := range tests {
+		got, err := Split(line)
+		if err != nil {
+			t.Errorf("Split(%q) failed: %v", line, err)
+			continue
+		}
+		if strings.Join(got, "|") != strings.Join(want, "|") || len(got) != len(want) {
+			t.Errorf("Split(%q) = %q, want %q", line, got, want)
+		}
+	}
+
+	for _, line := range []string{`echo "open`, `echo 'open`, `echo \`} {
+		if _, err := Split(line); err == nil {
+			t.Errorf("Split(%q) should fail", line)
+		}
+	}
+}
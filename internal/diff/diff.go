@@ -0,0 +1,150 @@
+// Package diff compares and merges text files line by line
+package diff
+
+import "strings"
+
+// OpKind is the kind of an edit operation
+type OpKind int
+
+const (
+	Equal  OpKind = iota // line is in both a and b
+	Delete               // line is only in a
+	Insert               // line is only in b
+)
+
+// Op is one line of an edit script. A is the line index in a for Equal and
+// Delete, B the line index in b for Equal and Insert; the other is -1.
+type Op struct {
+	Kind OpKind
+	A, B int
+}
+
+// Lines splits s into lines, keeping the line endings. A last line without a
+// newline is kept as it is.
+func Lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Diff returns the shortest edit script turning a into b
+func Diff(a, b []string) []Op {
+	// Common prefix and suffix need no search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, Op{Kind: Equal, A: i, B: i})
+	}
+	for _, op := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		if op.A >= 0 {
+			op.A += prefix
+		}
+		if op.B >= 0 {
+			op.B += prefix
+		}
+		ops = append(ops, op)
+	}
+	for i := suffix; i > 0; i-- {
+		ops = append(ops, Op{Kind: Equal, A: len(a) - i, B: len(b) - i})
+	}
+	return ops
+}
+
+// myers implements the O((N+M)D) algorithm from Myers' "An O(ND) Difference
+// Algorithm and Its Variations", keeping every round to walk back the path
+func myers(a, b []string) []Op {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	offset := max + 1
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	// Find the furthest reaching paths until one reaches the end of both
+	var x, y int
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1] // down: insert
+			} else {
+				x = v[offset+k-1] + 1 // right: delete
+			}
+			y = x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk back from the end, collecting the operations in reverse
+	var ops []Op
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, Op{Kind: Equal, A: x, B: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, Op{Kind: Insert, A: -1, B: prevY})
+			} else {
+				ops = append(ops, Op{Kind: Delete, A: prevX, B: -1})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// matches maps every line of a to the line of b it is kept as, or -1
+func matches(a, b []string) []int {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+	for _, op := range Diff(a, b) {
+		if op.Kind == Equal {
+			match[op.A] = op.B
+		}
+	}
+	return match
+}
@@ -0,0 +1,162 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+// apply rebuilds b from a and an edit script
+func apply(a, b []string, ops []Op) []string {
+	var out []string
+	for _, op := range ops {
+		switch op.Kind {
+		case Equal:
+			out = append(out, a[op.A])
+		case Insert:
+			out = append(out, b[op.B])
+		}
+	}
+	return out
+}
+
+func TestLines(t *testing.T) {
+	tests := map[string][]string{
+		"":         nil,
+		"a":        {"a"},
+		"a\n":      {"a\n"},
+		"a\nb":     {"a\n", "b"},
+		"a\n\nb\n": {"a\n", "\n", "b\n"},
+	}
+	for s, want := range tests {
+		if got := Lines(s); !equalLines(got, want) {
+			t.Errorf("Lines(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"", ""},
+		{"", "a\nb\n"},
+		{"a\nb\n", ""},
+		{"a\nb\nc\n", "a\nb\nc\n"},
+		{"a\nb\nc\nd\n", "a\nc\nd\ne\n"},
+		{"x\na\nb\n", "a\nb\ny\n"},
+		{"a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n"},
+	}
+	for _, tt := range tests {
+		a, b := Lines(tt.a), Lines(tt.b)
+		ops := Diff(a, b)
+		if got := apply(a, b, ops); !equalLines(got, b) {
+			t.Errorf("Diff(%q, %q) does not rebuild b: %q", tt.a, tt.b, got)
+		}
+	}
+
+	// The script is minimal: one changed line costs one delete and one insert
+	ops := Diff(Lines("a\nb\nc\n"), Lines("a\nx\nc\n"))
+	edits := 0
+	for _, op := range ops {
+		if op.Kind != Equal {
+			edits++
+		}
+	}
+	if edits != 2 {
+		t.Errorf("Expected 2 edits, got %d: %+v", edits, ops)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := "package main\n\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {}\n"
+
+	tests := []struct {
+		name      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts int
+	}{
+		{
+			name:   "only theirs changed",
+			ours:   base,
+			theirs: strings.Replace(base, "func b() {}", "func b() { return }", 1),
+			want:   strings.Replace(base, "func b() {}", "func b() { return }", 1),
+		},
+		{
+			name:   "both changed different regions",
+			ours:   strings.Replace(base, "func a() {}", "func a() { ours() }", 1),
+			theirs: strings.Replace(base, "func c() {}", "func c() { theirs() }", 1),
+			want:   "package main\n\nfunc a() { ours() }\n\nfunc b() {}\n\nfunc c() { theirs() }\n",
+		},
+		{
+			name:   "both made the same change",
+			ours:   base + "// end\n",
+			theirs: base + "// end\n",
+			want:   base + "// end\n",
+		},
+		{
+			name:      "both changed the same line",
+			ours:      strings.Replace(base, "func b() {}", "func b() { ours() }", 1),
+			theirs:    strings.Replace(base, "func b() {}", "func b() { theirs() }", 1),
+			want:      "package main\n\nfunc a() {}\n\n<<<<<<< local\nfunc b() { ours() }\n=======\nfunc b() { theirs() }\n>>>>>>> template\n\nfunc c() {}\n",
+			conflicts: 1,
+		},
+	}
+	for _, tt := range tests {
+		got, conflicts := Merge(base, tt.ours, tt.theirs, "local", "template")
+		if got != tt.want || conflicts != tt.conflicts {
+			t.Errorf("%s: Merge() = %d conflicts:\n%s\nwant %d conflicts:\n%s", tt.name, conflicts, got, tt.conflicts, tt.want)
+		}
+	}
+}
+
+func TestConflict(t *testing.T) {
+	got := Conflict("a", "b\n", "local", "template")
+	want := "<<<<<<< local\na\n=======\nb\n>>>>>>> template\n"
+	if got != want {
+		t.Errorf("Conflict() = %q, want %q", got, want)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n12\n13"
+	want := `--- a/f
++++ b/f
+@@ -1,5 +1,5 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+@@ -9,4 +9,4 @@
+ 9
+ 10
+-11
+ 12
++13
+\ No newline at end of file
+`
+	if got := Unified("a/f", "b/f", a, b, 2); got != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := Unified("a/f", "b/f", a, a, 3); got != "" {
+		t.Errorf("Unified() of equal input = %q, want \"\"", got)
+	}
+
+	// A new file is one hunk against an empty side
+	want = "--- /dev/null\n+++ b/f\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if got := Unified("/dev/null", "b/f", "", "x\ny\n", 3); got != want {
+		t.Errorf("Unified() for a new file =\n%s\nwant\n%s", got, want)
+	}
+
+	// Changes closer than twice the context share a hunk
+	if got := Unified("a", "b", a, b, 4); strings.Count(got, "@@ -") != 1 {
+		t.Errorf("Expected a single hunk:\n%s", got)
+	}
+
+	if added, deleted := Stat(a, b); added != 2 || deleted != 2 {
+		t.Errorf("Stat() = %d, %d, want 2, 2", added, deleted)
+	}
+}
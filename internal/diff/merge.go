@@ -0,0 +1,103 @@
+package diff
+
+import "strings"
+
+// Conflict markers, as written by git
+const (
+	markerOurs   = "<<<<<<<"
+	markerSep    = "======="
+	markerTheirs = ">>>>>>>"
+)
+
+// Merge combines the changes from base to ours and from base to theirs, like
+// diff3. Regions changed differently on both sides are written between
+// conflict markers labelled with oursLabel and theirsLabel. It returns the
+// merged text and the number of conflicts.
+func Merge(base, ours, theirs, oursLabel, theirsLabel string) (string, int) {
+	o, a, b := Lines(base), Lines(ours), Lines(theirs)
+	matchA, matchB := matches(o, a), matches(o, b)
+
+	var out strings.Builder
+	conflicts := 0
+	i, ia, ib := 0, 0, 0
+	for i < len(o) || ia < len(a) || ib < len(b) {
+		// Lines unchanged on both sides
+		for i < len(o) && matchA[i] == ia && matchB[i] == ib {
+			out.WriteString(o[i])
+			i, ia, ib = i+1, ia+1, ib+1
+		}
+		if i >= len(o) && ia >= len(a) && ib >= len(b) {
+			break
+		}
+
+		// The next base line both sides kept ends the changed region
+		next, nextA, nextB := len(o), len(a), len(b)
+		for j := i; j < len(o); j++ {
+			if matchA[j] >= 0 && matchB[j] >= 0 {
+				next, nextA, nextB = j, matchA[j], matchB[j]
+				break
+			}
+		}
+
+		chunkO, chunkA, chunkB := o[i:next], a[ia:nextA], b[ib:nextB]
+		switch {
+		case equalLines(chunkA, chunkO):
+			writeLines(&out, chunkB)
+		case equalLines(chunkB, chunkO), equalLines(chunkA, chunkB):
+			writeLines(&out, chunkA)
+		default:
+			conflicts++
+			out.WriteString(markerOurs + " " + oursLabel + "\n")
+			writeLines(&out, terminated(chunkA))
+			out.WriteString(markerSep + "\n")
+			writeLines(&out, terminated(chunkB))
+			out.WriteString(markerTheirs + " " + theirsLabel + "\n")
+		}
+		i, ia, ib = next, nextA, nextB
+	}
+
+	return out.String(), conflicts
+}
+
+// Conflict writes ours and theirs as a single conflict, for files without a
+// common base
+func Conflict(ours, theirs, oursLabel, theirsLabel string) string {
+	var out strings.Builder
+	out.WriteString(markerOurs + " " + oursLabel + "\n")
+	writeLines(&out, terminated(Lines(ours)))
+	out.WriteString(markerSep + "\n")
+	writeLines(&out, terminated(Lines(theirs)))
+	out.WriteString(markerTheirs + " " + theirsLabel + "\n")
+	return out.String()
+}
+
+// equalLines reports whether a and b hold the same lines
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// terminated makes sure the last line ends with a newline, so a conflict
+// marker after it starts on its own line
+func terminated(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	out := append([]string(nil), lines...)
+	out[len(out)-1] += "\n"
+	return out
+}
+
+// writeLines writes lines to out
+func writeLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+	}
+}
@@ -0,0 +1,140 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unified returns the changes from a to b in unified diff format, with
+// context unchanged lines around every change, or "" when a and b are equal.
+// aName and bName label the "---" and "+++" lines.
+func Unified(aName, bName, a, b string, context int) string {
+	al, bl := Lines(a), Lines(b)
+	ops := Diff(al, bl)
+
+	var out strings.Builder
+	for _, h := range hunks(ops, context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+		}
+
+		// Line numbers are 1-based; an empty side names the line before it
+		aStart, bStart, aCount, bCount := h.a, h.b, 0, 0
+		for _, op := range ops[h.start:h.end] {
+			if op.Kind != Insert {
+				aCount++
+			}
+			if op.Kind != Delete {
+				bCount++
+			}
+		}
+		if aCount > 0 {
+			aStart++
+		}
+		if bCount > 0 {
+			bStart++
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+
+		for _, op := range ops[h.start:h.end] {
+			switch op.Kind {
+			case Equal:
+				writeDiffLine(&out, " ", al[op.A])
+			case Delete:
+				writeDiffLine(&out, "-", al[op.A])
+			case Insert:
+				writeDiffLine(&out, "+", bl[op.B])
+			}
+		}
+	}
+	return out.String()
+}
+
+// Stat returns the number of lines added to and deleted from a to get b
+func Stat(a, b string) (added, deleted int) {
+	for _, op := range Diff(Lines(a), Lines(b)) {
+		switch op.Kind {
+		case Insert:
+			added++
+		case Delete:
+			deleted++
+		}
+	}
+	return added, deleted
+}
+
+// hunk is a range of ops printed together; a and b count the lines of each
+// side before it
+type hunk struct {
+	start, end int
+	a, b       int
+}
+
+// hunks groups the changes of ops with their context, joining groups whose
+// context would overlap
+func hunks(ops []Op, context int) []hunk {
+	var result []hunk
+	a, b := 0, 0
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == Equal {
+			a++
+			b++
+			i++
+			continue
+		}
+
+		// Start with the context before the change
+		start := max(i-context, 0)
+		if len(result) > 0 && start < result[len(result)-1].end {
+			start = result[len(result)-1].end
+		}
+		h := hunk{start: start, a: a - (i - start), b: b - (i - start)}
+
+		// Extend while the next change is close enough to share context
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != Equal {
+				if ops[end].Kind == Delete {
+					a++
+				} else {
+					b++
+				}
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == Equal {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				break
+			}
+			a += run - end
+			b += run - end
+			end = run
+		}
+		h.end = min(end+context, len(ops))
+		a += h.end - end
+		b += h.end - end
+		i = h.end
+
+		result = append(result, h)
+	}
+	return result
+}
+
+// hunkRange formats the start and length of one side of a hunk header
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// writeDiffLine writes a line with its prefix, marking a missing final newline
+func writeDiffLine(out *strings.Builder, prefix, line string) {
+	out.WriteString(prefix + line)
+	if !strings.HasSuffix(line, "\n") {
+		out.WriteString("\n\\ No newline at end of file\n")
+	}
+}
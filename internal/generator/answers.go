@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadAnswers reads a ProjectConfig from a YAML or JSON answers file.
+// The format is chosen by the file extension; ".json" is JSON, anything else YAML.
+func LoadAnswers(path string) (ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ProjectConfig{}, fmt.Errorf("failed to read answers file %s: %w", path, err)
+	}
+
+	config, err := parseAnswers(data, isJSONFile(path))
+	if err != nil {
+		return ProjectConfig{}, fmt.Errorf("invalid answers file %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// SaveAnswers writes config to path as YAML or JSON, depending on the extension
+func SaveAnswers(path string, config ProjectConfig) error {
+	var data []byte
+	var err error
+
+	if isJSONFile(path) {
+		data, err = json.MarshalIndent(config, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(config)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode answers: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write answers file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// parseAnswers decodes answers, rejecting unknown fields so typos are not ignored
+func parseAnswers(data []byte, isJSON bool) (ProjectConfig, error) {
+	var config ProjectConfig
+
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			return ProjectConfig{}, err
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		// An empty file decodes to io.EOF, which is just an empty config
+		if err := decoder.Decode(&config); err != nil && len(bytes.TrimSpace(data)) > 0 {
+			return ProjectConfig{}, err
+		}
+	}
+
+	// Apply the same rules as the interactive prompt
+	if config.ProjectName != "" {
+		if err := ValidateProjectName(config.ProjectName); err != nil {
+			return ProjectConfig{}, err
+		}
+	}
+
+	return config, nil
+}
+
+// isJSONFile reports whether path has a .json extension
+func isJSONFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAnswers(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "go-ten.yaml")
+	yamlContent := "projectName: my-api\nmoduleName: github.com/ourorg/my-api\nappType: web-api\npackage: stdlib\nvars:\n  port: 8080\n"
+	if err := os.WriteFile(yamlPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadAnswers(yamlPath)
+	if err != nil {
+		t.Fatalf("LoadAnswers failed: %v", err)
+	}
+	if config.ProjectName != "my-api" || config.ModuleName != "github.com/ourorg/my-api" || config.Package != "stdlib" {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+	if config.Vars["port"] != 8080 {
+		t.Errorf("Expected var port 8080, got %v", config.Vars["port"])
+	}
+
+	jsonPath := filepath.Join(dir, "go-ten.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"projectName": "my-api", "appType": "web-api"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAnswers(jsonPath); err != nil {
+		t.Errorf("LoadAnswers failed on JSON: %v", err)
+	}
+}
+
+func TestLoadAnswersInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	invalid := map[string]string{
+		"name.yaml":    "projectName: my project\n",
+		"unknown.yaml": "projectNmae: my-api\n",
+		"unknown.json": `{"projectNmae": "my-api"}`,
+	}
+	for name, content := range invalid {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadAnswers(path); err == nil {
+			t.Errorf("LoadAnswers should have failed for %s", name)
+		}
+	}
+}
+
+func TestSaveAnswersRoundTrip(t *testing.T) {
+	config := ProjectConfig{
+		ProjectName: "my-api",
+		ModuleName:  "my-api",
+		AppType:     "web-api",
+		Package:     "stdlib",
+		TargetDir:   "./my-api/",
+		Vars:        map[string]any{"author": "team"},
+	}
+
+	for _, name := range []string{"answers.yaml", "answers.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := SaveAnswers(path, config); err != nil {
+			t.Fatalf("SaveAnswers failed: %v", err)
+		}
+
+		loaded, err := LoadAnswers(path)
+		if err != nil {
+			t.Fatalf("LoadAnswers failed: %v", err)
+		}
+		if loaded.ProjectName != config.ProjectName || loaded.TargetDir != config.TargetDir || loaded.Vars["author"] != "team" {
+			t.Errorf("Round trip mismatch for %s: %+v", name, loaded)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/manuelbamise/go-ten/internal/cache"
+)
+
+// CacheTemplates adds every template of source to the user template cache.
+// source is a template directory, a directory of templates, a .zip, .tar or
+// .tar.gz archive of either, or a "git+<url>@<ref>" source.
+func CacheTemplates(source string) ([]cache.Entry, error) {
+	dir := source
+	if !cache.IsGitSource(source) {
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", source, err)
+		}
+		source, dir = abs, abs
+	}
+
+	// Archives are unpacked into a scratch directory first
+	if cache.IsArchive(source) {
+		tmp, err := os.MkdirTemp("", "go-ten-archive-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+
+		dir, err = cache.Extract(source, tmp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	templates, err := LoadTemplateDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("no templates found in %s", source)
+	}
+
+	index, err := cache.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []cache.Entry
+	for _, tmpl := range templates {
+		entry, err := index.Store(tmpl.Path, cache.Entry{
+			ID:      tmpl.ID(),
+			Name:    tmpl.Name,
+			Version: tmpl.Version,
+			Source:  source,
+			Commit:  tmpl.Commit,
+		})
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
+// cachedTemplates loads the templates in the user template cache. Templates
+// that cannot be read are left out and reported in the error, next to the
+// ones that could.
+func cachedTemplates() ([]Template, error) {
+	index, err := cache.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []Template
+	var errs []error
+	for _, entry := range index.Templates {
+		found, err := LoadTemplateDir(index.Path(entry.ID))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cached template %s is unreadable, run 'go-ten template update %s': %w", entry.ID, entry.ID, err))
+			continue
+		}
+		for _, tmpl := range found {
+			tmpl.Source = entry.Source
+			tmpl.Commit = entry.Commit
+			tmpl.Digest = entry.Digest
+			templates = append(templates, tmpl)
+		}
+	}
+	return templates, errors.Join(errs...)
+}
+
+// verify checks a cached template against the digest recorded when it was
+// added. Other templates are not checked.
+func (t *Template) verify() error {
+	if t.Digest == "" {
+		return nil
+	}
+
+	index, err := cache.Open()
+	if err != nil {
+		return err
+	}
+	return index.Verify(t.ID())
+}
+
+// markUsed records in the cache index that a cached template generated files,
+// which keeps it from being pruned. Other templates are not recorded.
+func (t *Template) markUsed() {
+	if t == nil || t.Digest == "" {
+		return
+	}
+
+	// The files are written already, so a failure only affects pruning
+	index, err := cache.Open()
+	if err == nil {
+		err = index.Touch(t.ID())
+	}
+	if err != nil {
+		warnf("failed to record the use of template %s: %v", t.ID(), err)
+	}
+}
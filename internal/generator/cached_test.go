@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/manuelbamise/go-ten/internal/cache"
+)
+
+func TestCacheTemplates(t *testing.T) {
+	isolateTemplateDirs(t)
+	src := t.TempDir()
+	writeTemplate(t, filepath.Join(src, "worker"), "worker", "stdlib", map[string]string{"go.mod.tmpl": "module {{.ModuleName}}\n"})
+	writeTemplate(t, filepath.Join(src, "cli"), "cli", "stdlib", map[string]string{"main.go": "package main\n"})
+
+	entries, err := CacheTemplates(src)
+	if err != nil {
+		t.Fatalf("CacheTemplates failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 cached templates, got %d", len(entries))
+	}
+
+	// Cached templates are found after the source is gone
+	if err := os.RemoveAll(src); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := FindTemplate("worker", "stdlib")
+	if err != nil {
+		t.Fatalf("FindTemplate failed: %v", err)
+	}
+	if tmpl.Source != src || tmpl.Digest == "" {
+		t.Errorf("Unexpected cached template source %q and digest %q", tmpl.Source, tmpl.Digest)
+	}
+
+	// A dry run leaves the cache index alone
+	config := ProjectConfig{ProjectName: "w", ModuleName: "example.com/w", AppType: "worker", Package: "stdlib", TargetDir: t.TempDir()}
+	if _, err := DryRun(config); err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	index, err := cache.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := index.Get("worker-stdlib"); !entry.LastUsed.IsZero() {
+		t.Error("A dry run should not record the use of a cached template")
+	}
+
+	// Generating records the time
+	config.TargetDir = filepath.Join(t.TempDir(), "w")
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if index, err = cache.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if entry, _ := index.Get("worker-stdlib"); entry.LastUsed.IsZero() {
+		t.Error("Using a cached template should record the time")
+	}
+	config.TargetDir = t.TempDir()
+
+	// Modified cached files are refused before anything is generated
+	if err := os.WriteFile(filepath.Join(index.Path("worker-stdlib"), "go.mod.tmpl"), []byte("module evil\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Generate(config); err == nil {
+		t.Error("Generate should fail for a modified cached template")
+	}
+	if entries, _ := os.ReadDir(config.TargetDir); len(entries) != 0 {
+		t.Error("Generate wrote files from a modified cached template")
+	}
+}
@@ -0,0 +1,178 @@
+package generator
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FileRule includes or excludes template paths depending on the config, or
+// turns off formatting for them. Conditions are template expressions such as
+// "{{.Vars.docker}}" or `{{eq .Vars.db "postgres"}}`; the surrounding braces
+// may be omitted.
+type FileRule struct {
+	Paths   []string `yaml:"paths"`   // template paths or globs; a directory matches everything below it
+	Include string   `yaml:"include"` // keep the paths only when this is true
+	Exclude string   `yaml:"exclude"` // drop the paths when this is true
+	Format  *bool    `yaml:"format"`  // false keeps generated .go files as rendered instead of gofmt'ing them
+}
+
+// validate checks that the rule has paths and parseable conditions
+func (r FileRule) validate() error {
+	if len(r.Paths) == 0 {
+		return fmt.Errorf("paths are required")
+	}
+	if r.Include == "" && r.Exclude == "" && r.Format == nil {
+		return fmt.Errorf("include, exclude or format is required")
+	}
+	for _, pattern := range r.Paths {
+		if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+			return fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+		}
+	}
+	for _, cond := range []string{r.Include, r.Exclude} {
+		if cond == "" {
+			continue
+		}
+		if _, err := newTemplate("condition").Parse(conditionTemplate(cond)); err != nil {
+			return fmt.Errorf("invalid condition %q: %w", cond, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the slash separated template path is covered by the rule
+func (r FileRule) matches(name string) bool {
+	for _, pattern := range r.Paths {
+		prefix := strings.TrimSuffix(pattern, "/**")
+		if name == prefix || strings.HasPrefix(name, prefix+"/") {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether the template path is generated for config
+func (m *Manifest) included(name string, config ProjectConfig) (bool, error) {
+	for _, rule := range m.Files {
+		if !rule.matches(name) {
+			continue
+		}
+
+		if rule.Include != "" {
+			ok, err := evalCondition(rule.Include, config)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				return false, nil
+			}
+		}
+
+		if rule.Exclude != "" {
+			ok, err := evalCondition(rule.Exclude, config)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return false, nil
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// formatted reports whether the Go file generated from the template path is
+// formatted
+func (m *Manifest) formatted(name string) bool {
+	for _, rule := range m.Files {
+		if rule.Format != nil && !*rule.Format && rule.matches(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// evalCondition renders a condition and reports whether the result is true
+func evalCondition(cond string, config ProjectConfig) (bool, error) {
+	result, err := processTemplate(conditionTemplate(cond), config)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate condition %q: %w", cond, err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(result)) {
+	case "", "false", "0", "no", "<no value>", "[]", "map[]":
+		return false, nil
+	}
+	return true, nil
+}
+
+// conditionTemplate wraps a bare expression in template braces
+func conditionTemplate(cond string) string {
+	if strings.Contains(cond, "{{") {
+		return cond
+	}
+	return "{{" + cond + "}}"
+}
+
+// renderPath renders every templated segment of a slash separated path with
+// the same engine as file contents. It returns false if a segment renders
+// empty, which drops the entry, and fails if the result would escape the
+// target directory.
+func renderPath(name string, config ProjectConfig) (string, bool, error) {
+	if !strings.Contains(name, "{{") {
+		return name, true, nil
+	}
+
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		if !strings.Contains(segment, "{{") {
+			continue
+		}
+
+		rendered, err := processTemplate(segment, config)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to render path %s: %w", name, err)
+		}
+		if strings.TrimSpace(rendered) == "" {
+			return "", false, nil
+		}
+		segments[i] = rendered
+	}
+
+	rendered := strings.Join(segments, "/")
+	if err := checkSafePath(rendered); err != nil {
+		return "", false, fmt.Errorf("unsafe path %q rendered from %s: %w", rendered, name, err)
+	}
+
+	return rendered, true, nil
+}
+
+// checkSafePath rejects rendered paths that are absolute, climb out of the
+// target directory with "..", or are otherwise not plain relative paths
+func checkSafePath(name string) error {
+	switch {
+	case strings.Contains(name, "\\"):
+		return fmt.Errorf("backslashes are not allowed")
+	case path.IsAbs(name) || filepath.IsAbs(name):
+		return fmt.Errorf("absolute paths are not allowed")
+	}
+
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return fmt.Errorf("\"..\" is not allowed")
+		}
+	}
+
+	if !fs.ValidPath(name) || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return fmt.Errorf("not a valid relative path")
+	}
+
+	return nil
+}
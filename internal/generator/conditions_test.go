@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"testing"
+)
+
+func TestFileRuleMatches(t *testing.T) {
+	rule := FileRule{Paths: []string{"deploy", "middleware/*.go.tmpl", "docs/**"}}
+
+	matching := []string{"deploy", "deploy/Dockerfile", "middleware/cors.go.tmpl", "docs", "docs/a/b.md"}
+	for _, name := range matching {
+		if !rule.matches(name) {
+			t.Errorf("Expected %s to match", name)
+		}
+	}
+
+	other := []string{"deployment", "middleware/sub/cors.go.tmpl", "cmd/main.go.tmpl"}
+	for _, name := range other {
+		if rule.matches(name) {
+			t.Errorf("Expected %s not to match", name)
+		}
+	}
+}
+
+func TestEvalCondition(t *testing.T) {
+	config := ProjectConfig{Vars: map[string]any{"docker": true, "db": "sqlite", "features": []string{}}}
+
+	tests := map[string]bool{
+		".Vars.docker":                    true,
+		"{{not .Vars.docker}}":            false,
+		`eq .Vars.db "sqlite"`:            true,
+		`{{if eq .Vars.db "pg"}}x{{end}}`: false,
+		".Vars.features":                  false,
+		".Vars.missing":                   false,
+	}
+
+	for cond, want := range tests {
+		got, err := evalCondition(cond, config)
+		if err != nil {
+			t.Errorf("evalCondition(%q) failed: %v", cond, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("evalCondition(%q) = %v, want %v", cond, got, want)
+		}
+	}
+}
+
+func TestRenderPath(t *testing.T) {
+	config := ProjectConfig{ProjectName: "my-api", Vars: map[string]any{"docker": false}}
+
+	got, ok, err := renderPath("{{if .Vars.docker}}deploy{{end}}/Dockerfile.tmpl", config)
+	if err != nil || ok {
+		t.Errorf("Expected empty segment to drop the path, got %q, %v, %v", got, ok, err)
+	}
+
+	config.Vars["docker"] = true
+	got, ok, err = renderPath("{{if .Vars.docker}}deploy{{end}}/Dockerfile.tmpl", config)
+	if err != nil || !ok || got != "deploy/Dockerfile.tmpl" {
+		t.Errorf("Expected deploy/Dockerfile.tmpl, got %q, %v, %v", got, ok, err)
+	}
+}
+
+func TestDryRunConditionalFiles(t *testing.T) {
+	config := ProjectConfig{
+		ProjectName: "test-project",
+		ModuleName:  "test-project",
+		AppType:     "web-api",
+		Package:     "stdlib",
+		TargetDir:   t.TempDir(),
+		Vars:        map[string]any{"cors": false, "metrics": true, "docker": true},
+	}
+
+	plan, err := DryRun(config)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+
+	paths := map[string]bool{}
+	for _, entry := range plan.Entries {
+		paths[entry.Path] = true
+	}
+
+	for _, name := range []string{"middleware/metrics.go", "deploy", "deploy/Dockerfile"} {
+		if !paths[name] {
+			t.Errorf("Expected %s to be generated", name)
+		}
+	}
+	if paths["middleware/cors.go"] {
+		t.Error("middleware/cors.go should be excluded")
+	}
+
+	// The defaults leave out the optional pieces
+	config.Vars = nil
+	plan, err = DryRun(config)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	for _, entry := range plan.Entries {
+		if entry.Path == "deploy" || entry.Path == "middleware/metrics.go" {
+			t.Errorf("%s should not be generated by default", entry.Path)
+		}
+	}
+}
+
+func TestRenderPathSafety(t *testing.T) {
+	config := ProjectConfig{ProjectName: "my-api", Vars: map[string]any{"resource": "users"}}
+
+	got, ok, err := renderPath("internal/{{.Vars.resource}}/handler.go.tmpl", config)
+	if err != nil || !ok || got != "internal/users/handler.go.tmpl" {
+		t.Errorf("Expected internal/users/handler.go.tmpl, got %q, %v, %v", got, ok, err)
+	}
+
+	unsafe := []string{"..", "../evil", "a/../../evil", "/etc", "a\\b", "a//b"}
+	for _, value := range unsafe {
+		config.Vars["resource"] = value
+		if _, _, err := renderPath("internal/{{.Vars.resource}}/handler.go", config); err == nil {
+			t.Errorf("renderPath should have rejected %q", value)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictPolicy decides what happens when a generated file already exists
+type ConflictPolicy string
+
+const (
+	ConflictAbort     ConflictPolicy = "abort"     // stop before anything is written
+	ConflictSkip      ConflictPolicy = "skip"      // keep the existing file
+	ConflictOverwrite ConflictPolicy = "overwrite" // replace the existing file
+	ConflictNew       ConflictPolicy = "new"       // write the generated file next to it with a .new suffix, never replacing a file
+)
+
+// ConflictPolicies lists the valid policies
+var ConflictPolicies = []ConflictPolicy{ConflictAbort, ConflictSkip, ConflictOverwrite, ConflictNew}
+
+// ParseConflictPolicy converts a policy name to a ConflictPolicy
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	for _, policy := range ConflictPolicies {
+		if string(policy) == name {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("invalid conflict policy %q (valid: abort, skip, overwrite, new)", name)
+}
+
+// ConflictError is returned when existing files block generation
+type ConflictError struct {
+	Paths []string // slash separated, relative to the target directory
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%d file(s) already exist in the target directory: %s", len(e.Paths), strings.Join(e.Paths, ", "))
+}
+
+// DetectConflicts renders the template for config and returns the files that
+// already exist in the target directory
+func DetectConflicts(config ProjectConfig) ([]string, error) {
+	plan, err := DryRun(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.Conflicts(config.TargetDir), nil
+}
+
+// Conflicts returns the planned files that already exist below targetDir,
+// and the planned directories whose path is taken by something else than a
+// directory
+func (p *Plan) Conflicts(targetDir string) []string {
+	var conflicts []string
+	for _, entry := range p.Entries {
+		targetPath := filepath.Join(targetDir, filepath.FromSlash(entry.Path))
+		if _, err := os.Lstat(targetPath); err != nil {
+			continue
+		}
+
+		// An existing directory, or a link to one, is reused
+		if entry.IsDir {
+			if info, err := os.Stat(targetPath); err == nil && info.IsDir() {
+				continue
+			}
+		}
+		conflicts = append(conflicts, entry.Path)
+	}
+	return conflicts
+}
+
+// resolveConflicts applies the conflict policies of config to the plan.
+// It fails with a *ConflictError if any conflicting file is set to abort.
+func (p *Plan) resolveConflicts(config ProjectConfig) error {
+	conflicts := p.Conflicts(config.TargetDir)
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	// Decide on a policy for every conflicting file first, so that nothing
+	// changes unless all conflicts can be resolved
+	policies := make(map[string]ConflictPolicy, len(conflicts))
+	var aborted []string
+	for _, path := range conflicts {
+		policy := config.conflictPolicy(path)
+		if policy == ConflictAbort {
+			aborted = append(aborted, path)
+		}
+		policies[path] = policy
+	}
+	if len(aborted) > 0 {
+		return &ConflictError{Paths: aborted}
+	}
+
+	// Paths taken by the plan, so .new files do not replace planned ones
+	taken := make(map[string]bool, len(p.Entries))
+	for _, entry := range p.Entries {
+		taken[entry.Path] = true
+	}
+
+	// Everything below a conflicting directory goes where the directory
+	// goes: moved[dir] is its new path, or empty when it is skipped
+	moved := map[string]string{}
+	entries := p.Entries[:0]
+	for _, entry := range p.Entries {
+		if dir, ok := movedParent(entry.Path, moved); ok {
+			if moved[dir] == "" {
+				continue
+			}
+			entry.planned = entry.Path
+			entry.Path = moved[dir] + strings.TrimPrefix(entry.Path, dir)
+			entries = append(entries, entry)
+			continue
+		}
+
+		switch policies[entry.Path] {
+		case ConflictSkip:
+			if entry.IsDir {
+				moved[entry.Path] = ""
+			}
+			continue
+		case ConflictNew:
+			// The lockfile keeps describing the original path
+			entry.planned = entry.Path
+			entry.Path = freeNewPath(config.TargetDir, entry.Path, taken)
+			taken[entry.Path] = true
+			if entry.IsDir {
+				moved[entry.planned] = entry.Path
+			}
+		}
+		entries = append(entries, entry)
+	}
+	p.Entries = entries
+
+	return nil
+}
+
+// movedParent returns the directory above the slash separated path p that
+// is in moved
+func movedParent(p string, moved map[string]string) (string, bool) {
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if _, ok := moved[dir]; ok {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// freeNewPath returns the path a conflicting file is written to with
+// ConflictNew: "<path>.new", or "<path>.new.<n>" when that exists already
+func freeNewPath(targetDir, path string, taken map[string]bool) string {
+	candidate := path + ".new"
+	for n := 2; ; n++ {
+		_, err := os.Lstat(filepath.Join(targetDir, filepath.FromSlash(candidate)))
+		if os.IsNotExist(err) && !taken[candidate] {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s.new.%d", path, n)
+	}
+}
+
+// conflictPolicy returns the policy for a conflicting path, preferring a
+// per-file resolution over the config wide policy
+func (c ProjectConfig) conflictPolicy(path string) ConflictPolicy {
+	if policy, ok := c.ConflictResolutions[path]; ok {
+		return policy
+	}
+	if c.OnConflict != "" {
+		return c.OnConflict
+	}
+	return ConflictAbort
+}
@@ -0,0 +1,269 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newConflictTarget creates a target directory with an existing go.mod
+func newConflictTarget(t *testing.T) (ProjectConfig, string) {
+	t.Helper()
+
+	testDir := t.TempDir()
+	goModPath := filepath.Join(testDir, "go.mod")
+	if err := os.WriteFile(goModPath, []byte("module existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := ProjectConfig{
+		ProjectName:   "test-project",
+		ModuleName:    "test-project",
+		AppType:       "web-api",
+		Package:       "stdlib",
+		TargetDir:     testDir,
+		UseCurrentDir: true,
+	}
+	return config, goModPath
+}
+
+func TestDetectConflicts(t *testing.T) {
+	config, _ := newConflictTarget(t)
+
+	conflicts, err := DetectConflicts(config)
+	if err != nil {
+		t.Fatalf("DetectConflicts failed: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0] != "go.mod" {
+		t.Errorf("Expected [go.mod], got %v", conflicts)
+	}
+}
+
+func TestGenerateConflictPolicies(t *testing.T) {
+	tests := []struct {
+		policy     ConflictPolicy
+		wantGoMod  string
+		wantNewMod bool
+		wantErr    bool
+	}{
+		{policy: "", wantGoMod: "module existing\n", wantErr: true},
+		{policy: ConflictAbort, wantGoMod: "module existing\n", wantErr: true},
+		{policy: ConflictSkip, wantGoMod: "module existing\n"},
+		{policy: ConflictOverwrite, wantGoMod: "module test-project\n\ngo 1.21"},
+		{policy: ConflictNew, wantGoMod: "module existing\n", wantNewMod: true},
+	}
+
+	for _, tt := range tests {
+		config, goModPath := newConflictTarget(t)
+		config.OnConflict = tt.policy
+
+		_, err := Generate(config)
+		var conflictErr *ConflictError
+		if tt.wantErr != errors.As(err, &conflictErr) {
+			t.Errorf("policy %q: unexpected error: %v", tt.policy, err)
+		}
+
+		content, _ := os.ReadFile(goModPath)
+		if string(content) != tt.wantGoMod {
+			t.Errorf("policy %q: go.mod = %q, want %q", tt.policy, content, tt.wantGoMod)
+		}
+
+		_, statErr := os.Stat(goModPath + ".new")
+		if tt.wantNewMod != (statErr == nil) {
+			t.Errorf("policy %q: go.mod.new exists = %v", tt.policy, statErr == nil)
+		}
+
+		// Aborting must not write anything
+		_, statErr = os.Stat(filepath.Join(config.TargetDir, "test.txt"))
+		if tt.wantErr && statErr == nil {
+			t.Errorf("policy %q: files were written despite the conflict", tt.policy)
+		}
+	}
+}
+
+func TestConflictResolutionsOverridePolicy(t *testing.T) {
+	config, goModPath := newConflictTarget(t)
+	config.OnConflict = ConflictAbort
+	config.ConflictResolutions = map[string]ConflictPolicy{"go.mod": ConflictOverwrite}
+
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(goModPath)
+	if string(content) == "module existing\n" {
+		t.Error("go.mod should have been overwritten")
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	for _, name := range []string{"abort", "skip", "overwrite", "new"} {
+		if _, err := ParseConflictPolicy(name); err != nil {
+			t.Errorf("ParseConflictPolicy(%q) failed: %v", name, err)
+		}
+	}
+	if _, err := ParseConflictPolicy("merge"); err == nil {
+		t.Error("ParseConflictPolicy should have failed with invalid policy")
+	}
+}
+
+func TestConflictNewKeepsExistingFiles(t *testing.T) {
+	config, goModPath := newConflictTarget(t)
+	config.OnConflict = ConflictNew
+	if err := os.WriteFile(goModPath+".new", []byte("module mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// An existing go.mod.new is kept and the output moves on to a free name
+	content, _ := os.ReadFile(goModPath + ".new")
+	if string(content) != "module mine\n" {
+		t.Errorf("go.mod.new was replaced: %q", content)
+	}
+	content, err := os.ReadFile(goModPath + ".new.2")
+	if err != nil || string(content) != "module test-project\n\ngo 1.21" {
+		t.Errorf("Expected the generated go.mod in go.mod.new.2, got %q, %v", content, err)
+	}
+
+	// The lockfile records the file under the path the template planned
+	lock, err := ReadLock(config.TargetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lock.Files["go.mod"]; !ok {
+		t.Errorf("Expected go.mod in the lockfile, got %v", lock.Files)
+	}
+	for path := range lock.Files {
+		if strings.HasPrefix(path, "go.mod.new") {
+			t.Errorf("Unexpected %s in the lockfile", path)
+		}
+	}
+}
+
+func TestConflictFileOverDirectory(t *testing.T) {
+	isolateTemplateDirs(t)
+	templateDir := t.TempDir()
+	writeTemplate(t, templateDir, "tool", "plain", map[string]string{"notes.txt": "notes\n"})
+
+	tests := []struct {
+		policy  ConflictPolicy
+		wantErr bool
+		want    string // file generated from notes.txt
+	}{
+		{policy: ConflictAbort, wantErr: true},
+		{policy: ConflictSkip},
+		{policy: ConflictOverwrite, want: "notes.txt"},
+		{policy: ConflictNew, want: "notes.txt.new"},
+	}
+	for _, tt := range tests {
+		// notes.txt is a directory in the target
+		targetDir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(targetDir, "notes.txt", "old"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		config := ProjectConfig{ProjectName: "svc", AppType: "tool", Package: "plain", TargetDir: targetDir, Template: templateDir, OnConflict: tt.policy}
+
+		if conflicts, err := DetectConflicts(config); err != nil || len(conflicts) != 1 || conflicts[0] != "notes.txt" {
+			t.Errorf("policy %q: expected [notes.txt], got %v (%v)", tt.policy, conflicts, err)
+		}
+		_, err := Generate(config)
+		var conflictErr *ConflictError
+		if tt.wantErr != errors.As(err, &conflictErr) || !tt.wantErr && err != nil {
+			t.Errorf("policy %q: unexpected error: %v", tt.policy, err)
+			continue
+		}
+
+		if tt.want != "" {
+			if content, err := os.ReadFile(filepath.Join(targetDir, tt.want)); err != nil || string(content) != "notes\n" {
+				t.Errorf("policy %q: expected the generated %s, got %q (%v)", tt.policy, tt.want, content, err)
+			}
+		}
+		_, statErr := os.Stat(filepath.Join(targetDir, "notes.txt", "old"))
+		if keep := tt.policy != ConflictOverwrite; keep != (statErr == nil) {
+			t.Errorf("policy %q: directory kept = %v", tt.policy, statErr == nil)
+		}
+	}
+}
+
+func TestConflictDirectoryOverFile(t *testing.T) {
+	isolateTemplateDirs(t)
+	templateDir := t.TempDir()
+	writeTemplate(t, templateDir, "tool", "plain", map[string]string{"cmd/main.txt": "main\n", "cmd/run/run.txt": "run\n"})
+
+	tests := []struct {
+		policy  ConflictPolicy
+		wantErr bool
+		wantDir string // directory generated from cmd
+	}{
+		{policy: ConflictAbort, wantErr: true},
+		{policy: ConflictSkip},
+		{policy: ConflictOverwrite, wantDir: "cmd"},
+		{policy: ConflictNew, wantDir: "cmd.new"},
+	}
+	for _, tt := range tests {
+		// cmd is a file in the target
+		targetDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(targetDir, "cmd"), []byte("mine\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config := ProjectConfig{ProjectName: "svc", AppType: "tool", Package: "plain", TargetDir: targetDir, Template: templateDir, OnConflict: tt.policy}
+
+		if conflicts, err := DetectConflicts(config); err != nil || len(conflicts) != 1 || conflicts[0] != "cmd" {
+			t.Errorf("policy %q: expected [cmd], got %v (%v)", tt.policy, conflicts, err)
+		}
+		_, err := Generate(config)
+		var conflictErr *ConflictError
+		if tt.wantErr != errors.As(err, &conflictErr) || !tt.wantErr && err != nil {
+			t.Errorf("policy %q: unexpected error: %v", tt.policy, err)
+			continue
+		}
+
+		if tt.wantDir != "" {
+			for _, file := range []string{"main.txt", "run/run.txt"} {
+				if _, err := os.Stat(filepath.Join(targetDir, tt.wantDir, file)); err != nil {
+					t.Errorf("policy %q: %s was not generated in %s: %v", tt.policy, file, tt.wantDir, err)
+				}
+			}
+		}
+		content, _ := os.ReadFile(filepath.Join(targetDir, "cmd"))
+		if keep := tt.policy != ConflictOverwrite; keep != (string(content) == "mine\n") {
+			t.Errorf("policy %q: cmd = %q", tt.policy, content)
+		}
+
+		// Files moved with their directory are recorded where the template planned them
+		if tt.policy == ConflictNew {
+			lock, err := ReadLock(targetDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := lock.Files["cmd/run/run.txt"]; !ok {
+				t.Errorf("Expected cmd/run/run.txt in the lockfile, got %v", lock.Files)
+			}
+		}
+	}
+}
+
+func TestConflictDirectoryOverFileRollsBack(t *testing.T) {
+	isolateTemplateDirs(t)
+	templateDir := t.TempDir()
+	writeTemplate(t, templateDir, "tool", "plain", map[string]string{"cmd/main.txt": "main\n"})
+	targetDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(targetDir, "cmd"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving the first file fails after cmd was backed up for the directory
+	failRenameAfter(t, 2)
+	config := ProjectConfig{ProjectName: "svc", AppType: "tool", Package: "plain", TargetDir: targetDir, Template: templateDir, OnConflict: ConflictOverwrite}
+	if _, err := Generate(config); err == nil {
+		t.Fatal("Generate should have failed")
+	}
+	if content, err := os.ReadFile(filepath.Join(targetDir, "cmd")); err != nil || string(content) != "mine\n" {
+		t.Errorf("cmd was not restored: %q (%v)", content, err)
+	}
+}
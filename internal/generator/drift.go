@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/manuelbamise/go-ten/internal/diff"
+	"golang.org/x/mod/modfile"
+)
+
+// DriftStatus says how a project file differs from the template output
+type DriftStatus string
+
+const (
+	DriftModified DriftStatus = "modified" // content differs
+	DriftMissing  DriftStatus = "missing"  // produced by the template but not in the project
+	DriftExtra    DriftStatus = "extra"    // generated before but no longer produced by the template
+)
+
+// FileDrift is the difference of one file. Added and Deleted count the lines
+// the project has on top of the template output and lacks from it.
+type FileDrift struct {
+	Path    string      `json:"path"`
+	Status  DriftStatus `json:"status"`
+	Added   int         `json:"added"`
+	Deleted int         `json:"deleted"`
+	Diff    string      `json:"diff"` // unified diff from the template output to the project file
+}
+
+// DriftResult compares a project with what its template produces today
+type DriftResult struct {
+	Template  LockTemplate  `json:"template"`
+	Config    ProjectConfig `json:"config"`
+	Files     []FileDrift   `json:"files"`     // files that differ, by path
+	Unchanged int           `json:"unchanged"` // number of files matching the template output
+}
+
+// DriftContext is the number of unchanged lines shown around changes
+const DriftContext = 3
+
+// InferConfig recovers the answers a project in dir was generated with: from
+// its lockfile when there is one, or else the project and module name from
+// its go.mod. The app type and package are only known from a lockfile.
+func InferConfig(dir string) (ProjectConfig, error) {
+	if lock, err := ReadLock(dir); err == nil {
+		config := lock.Config
+		config.TargetDir = dir
+		return config, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return ProjectConfig{}, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ProjectConfig{}, fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	modulePath := modfile.ModulePath(data)
+	if modulePath == "" {
+		return ProjectConfig{}, fmt.Errorf("no module path in %s", filepath.Join(dir, "go.mod"))
+	}
+
+	// Projects are named after the last element of the module path, unless it
+	// is not a valid name
+	name := path.Base(modulePath)
+	if ValidateProjectName(name) != nil {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return ProjectConfig{}, fmt.Errorf("failed to resolve project directory: %w", err)
+		}
+		name = filepath.Base(abs)
+	}
+
+	return ProjectConfig{ProjectName: name, ModuleName: modulePath, TargetDir: dir}, nil
+}
+
+// Drift renders the template for config in memory and compares it with the
+// project in config.TargetDir. Variables the template does not declare are
+// ignored, so answers recorded for an older template version still apply.
+func Drift(config ProjectConfig) (*DriftResult, error) {
+	plan, err := renderForUpdate(config)
+	if err != nil {
+		return nil, err
+	}
+	lock := plan.lock()
+	result := &DriftResult{Template: lock.Template, Config: lock.Config, Files: []FileDrift{}}
+
+	rendered := plan.Contents()
+	paths := make([]string, 0, len(rendered))
+	for p := range rendered {
+		paths = append(paths, p)
+	}
+
+	// Files of an earlier generation the template dropped since
+	previous, err := ReadLock(config.TargetDir)
+	if err == nil {
+		for p := range previous.Files {
+			if _, ok := rendered[p]; !ok {
+				paths = append(paths, p)
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		content, err := os.ReadFile(filepath.Join(config.TargetDir, filepath.FromSlash(p)))
+		exists := err == nil
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		want, inTemplate := rendered[p]
+
+		drift := FileDrift{Path: p}
+		switch {
+		case !inTemplate && !exists:
+			continue
+		case !inTemplate:
+			drift.Status = DriftExtra
+			drift.Diff = diff.Unified("/dev/null", "b/"+p, "", string(content), DriftContext)
+		case !exists:
+			drift.Status = DriftMissing
+			drift.Diff = diff.Unified("a/"+p, "/dev/null", string(want), "", DriftContext)
+		case string(content) == string(want):
+			result.Unchanged++
+			continue
+		case previous != nil && HashContent(want) == previous.Files[p] && HashContent(content) == previous.PostStepFiles[p]:
+			// The template output is unchanged and the post-generation steps
+			// rewrote it, like go.mod after go mod tidy
+			result.Unchanged++
+			continue
+		default:
+			drift.Status = DriftModified
+			drift.Diff = diff.Unified("a/"+p, "b/"+p, string(want), string(content), DriftContext)
+		}
+		drift.Added, drift.Deleted = diff.Stat(string(want), string(content))
+		result.Files = append(result.Files, drift)
+	}
+
+	return result, nil
+}
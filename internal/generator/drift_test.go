@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDrift(t *testing.T) {
+	isolateTemplateDirs(t)
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	config := ProjectConfig{
+		ProjectName: "my-api",
+		ModuleName:  "example.com/team/my-api",
+		AppType:     "web-api",
+		Package:     "stdlib",
+		TargetDir:   targetDir,
+	}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	inferred, err := InferConfig(targetDir)
+	if err != nil {
+		t.Fatalf("InferConfig failed: %v", err)
+	}
+	result, err := Drift(inferred)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if len(result.Files) != 0 || result.Unchanged == 0 {
+		t.Fatalf("A fresh project should not drift: %+v", result.Files)
+	}
+
+	// Change one file and delete another
+	mainPath := filepath.Join(targetDir, "cmd", "my-api", "main.go")
+	original, err := os.ReadFile(mainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mainPath, append(original, "// local change\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(targetDir, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = Drift(inferred)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	drifts := map[string]FileDrift{}
+	for _, f := range result.Files {
+		drifts[f.Path] = f
+	}
+	if len(drifts) != 2 {
+		t.Errorf("Expected 2 changed files, got %+v", result.Files)
+	}
+	if f := drifts["cmd/my-api/main.go"]; f.Status != DriftModified || f.Added != 1 || f.Deleted != 0 ||
+		!strings.Contains(f.Diff, "+++ b/cmd/my-api/main.go\n") || !strings.Contains(f.Diff, "+// local change\n") {
+		t.Errorf("Unexpected drift of main.go: %+v", f)
+	}
+	if f := drifts["go.mod"]; f.Status != DriftMissing || f.Added != 0 || !strings.Contains(f.Diff, "+++ /dev/null\n") {
+		t.Errorf("Unexpected drift of go.mod: %+v", f)
+	}
+}
+
+func TestInferConfigFromGoMod(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "checkout")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/team/my-api\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := InferConfig(dir)
+	if err != nil {
+		t.Fatalf("InferConfig failed: %v", err)
+	}
+	if config.ProjectName != "my-api" || config.ModuleName != "example.com/team/my-api" || config.TargetDir != dir {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+
+	// A last path element that is no project name falls back to the directory
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/team/api.v2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if config, err := InferConfig(dir); err != nil || config.ProjectName != "checkout" {
+		t.Errorf("InferConfig() = %+v, %v, want the directory name", config, err)
+	}
+
+	if _, err := InferConfig(t.TempDir()); err == nil {
+		t.Error("InferConfig should fail without go.mod or lockfile")
+	}
+}
@@ -0,0 +1,168 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestGeneratedProjects generates every embedded template, once with the
+// variable defaults and once with every option turned on, and checks that
+// the project builds, passes its own tests and serves its routes
+func TestGeneratedProjects(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated projects")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	isolateTemplateDirs(t)
+
+	templates, err := TemplatesIn("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range templates {
+		tmpl := &templates[i]
+		for _, all := range []bool{false, true} {
+			name := tmpl.ID() + "/defaults"
+			if all {
+				name = tmpl.ID() + "/all"
+			}
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+				testGeneratedProject(t, tmpl, (&linter{tmpl: tmpl}).sampleVars(all))
+			})
+		}
+	}
+}
+
+// testGeneratedProject generates tmpl with vars, builds and tests it, and
+// checks the routes of the server it builds
+func testGeneratedProject(t *testing.T, tmpl *Template, vars map[string]any) {
+	dir := filepath.Join(t.TempDir(), "my-project")
+	config := ProjectConfig{
+		ProjectName: "my-project",
+		ModuleName:  "example.com/my-project",
+		AppType:     tmpl.AppType,
+		Package:     tmpl.Package,
+		TargetDir:   dir,
+		Vars:        vars,
+	}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Standard library templates must build without the network
+	env := append(os.Environ(), "GOFLAGS=-mod=mod", "GOTOOLCHAIN=local")
+	if tmpl.Package == "stdlib" {
+		env = append(env, "GOPROXY=off")
+	}
+	bin := t.TempDir()
+	goCmd(t, dir, env, "build", "./...")
+	goCmd(t, dir, env, "test", "./...")
+	goCmd(t, dir, env, "build", "-o", bin+string(filepath.Separator), "./cmd/...")
+
+	base := startServer(t, filepath.Join(bin, config.ProjectName))
+	checkRoute(t, base+"/health", "status", "healthy")
+	checkRoute(t, base+"/api/v1/ping", "message", "pong")
+}
+
+// goCmd runs the go tool in dir, failing the test when it fails
+func goCmd(t *testing.T, dir string, env []string, args ...string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir, cmd.Env = dir, env
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go %v failed: %v\n%s", args, err, out)
+	}
+}
+
+// startServer starts the server binary on a free port and waits until it
+// accepts connections. The server is stopped when the test ends.
+func startServer(t *testing.T, bin string) string {
+	t.Helper()
+
+	// Find a free port; the server binds it right after
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	var output bytes.Buffer
+	cmd := exec.Command(bin)
+	cmd.Env = append(os.Environ(), "PORT="+strconv.Itoa(port))
+	cmd.Stdout, cmd.Stderr = &output, &output
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	t.Cleanup(func() {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			cmd.Process.Kill()
+		}
+		select {
+		case <-exited:
+		case <-time.After(10 * time.Second):
+			cmd.Process.Kill()
+			<-exited
+		}
+		if t.Failed() {
+			t.Logf("server output:\n%s", output.String())
+		}
+	})
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return "http://" + addr
+		}
+		select {
+		case err := <-exited:
+			exited <- err
+			t.Fatalf("server exited before accepting connections: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not accept connections on %s: %v", addr, err)
+		}
+	}
+}
+
+// checkRoute requests url and checks that it answers 200 with a JSON object
+// whose key has the value want
+func checkRoute(t *testing.T, url, key, want string) {
+	t.Helper()
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("GET %s returned invalid JSON: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK || fmt.Sprint(body[key]) != want {
+		t.Errorf("GET %s = %d %v, want 200 with %s %q", url, resp.StatusCode, body, key, want)
+	}
+}
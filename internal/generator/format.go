@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// formatGo formats Go source like gofmt and groups its imports like
+// goimports -local: the standard library first, then other modules, then
+// the packages of module. Source that does not parse is returned unchanged
+// with the error.
+func formatGo(src []byte, module string) ([]byte, error) {
+	// Group before gofmt, whose sorting can move comments between imports
+	grouped, err := groupImports(src, module)
+	if err != nil {
+		return src, err
+	}
+
+	formatted, err := format.Source(grouped)
+	if err != nil {
+		return src, fmt.Errorf("failed to format Go source: %w", err)
+	}
+	return formatted, nil
+}
+
+// importGroup orders the import groups: standard library, other modules, the
+// project's own packages
+func importGroup(importPath, module string) int {
+	switch {
+	case module != "" && (importPath == module || strings.HasPrefix(importPath, module+"/")):
+		return 2
+	case !strings.Contains(strings.SplitN(importPath, "/", 2)[0], "."):
+		return 0
+	default:
+		return 1
+	}
+}
+
+// groupImports rewrites every parenthesized import block of src into sorted
+// groups separated by blank lines
+func groupImports(src []byte, module string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.ImportsOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go source: %w", err)
+	}
+	tokFile := fset.File(file.Pos())
+
+	// Rewrite from the end so earlier offsets stay valid
+	for i := len(file.Decls) - 1; i >= 0; i-- {
+		decl, ok := file.Decls[i].(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT || !decl.Lparen.IsValid() || len(decl.Specs) < 2 {
+			continue
+		}
+		block, ok := importBlock(src, tokFile, file, decl, module)
+		if !ok {
+			continue
+		}
+		start, end := tokFile.Offset(decl.Pos()), tokFile.Offset(decl.End())
+		src = append(src[:start:start], append(block, src[end:]...)...)
+	}
+	return src, nil
+}
+
+// importBlock renders the grouped import declaration. It reports false for
+// blocks it cannot rewrite safely: cgo imports and comments that belong to
+// no import.
+func importBlock(src []byte, tokFile *token.File, file *ast.File, decl *ast.GenDecl, module string) ([]byte, bool) {
+	type spec struct {
+		path string
+		text string
+	}
+	var groups [3][]spec
+	attached := 0
+	for _, s := range decl.Specs {
+		imp := s.(*ast.ImportSpec)
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || importPath == "C" {
+			return nil, false
+		}
+
+		start, end := imp.Pos(), imp.End()
+		if imp.Doc != nil {
+			start = imp.Doc.Pos()
+			attached++
+		}
+		if imp.Comment != nil {
+			end = imp.Comment.End()
+			attached++
+		}
+		group := importGroup(importPath, module)
+		groups[group] = append(groups[group], spec{path: importPath, text: string(src[tokFile.Offset(start):tokFile.Offset(end)])})
+	}
+
+	// Free-standing comments would lose their place
+	inside := 0
+	for _, c := range file.Comments {
+		if c.Pos() > decl.Lparen && c.End() < decl.Rparen {
+			inside++
+		}
+	}
+	if inside != attached {
+		return nil, false
+	}
+
+	var b strings.Builder
+	b.WriteString("import (\n")
+	first := true
+	for _, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		if !first {
+			b.WriteString("\n")
+		}
+		first = false
+		sort.SliceStable(group, func(i, j int) bool { return group[i].path < group[j].path })
+		for _, s := range group {
+			b.WriteString("\t" + s.text + "\n")
+		}
+	}
+	b.WriteString(")")
+	return []byte(b.String()), true
+}
@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatGo(t *testing.T) {
+	src := `package main
+
+import (
+    "github.com/ourorg/my-api/utils"
+    "os"
+    // Logging
+    "log"
+    "golang.org/x/sync/errgroup"
+    h "github.com/ourorg/my-api/handlers" // handlers
+)
+
+func main() {
+    log.Println(os.Args, h.X, utils.Y, errgroup.Group{})
+}
+`
+	want := `package main
+
+import (
+	// Logging
+	"log"
+	"os"
+
+	"golang.org/x/sync/errgroup"
+
+	h "github.com/ourorg/my-api/handlers" // handlers
+	"github.com/ourorg/my-api/utils"
+)
+
+func main() {
+	log.Println(os.Args, h.X, utils.Y, errgroup.Group{})
+}
+`
+	got, err := formatGo([]byte(src), "github.com/ourorg/my-api")
+	if err != nil {
+		t.Fatalf("formatGo failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Blocks with free-standing comments keep their layout
+	src = "package main\n\nimport (\n\t\"os\"\n\n\t// the rest\n\n\t\"fmt\"\n)\n"
+	if got, err := formatGo([]byte(src), ""); err != nil || string(got) != src {
+		t.Errorf("Expected the block to be kept, got %q (%v)", got, err)
+	}
+
+	// Source that does not parse is returned as is
+	src = "package main\n\nfunc main() {\n"
+	if got, err := formatGo([]byte(src), ""); err == nil || string(got) != src {
+		t.Errorf("Expected an error and the source, got %q (%v)", got, err)
+	}
+}
+
+func TestGenerateFormatsGo(t *testing.T) {
+	isolateTemplateDirs(t)
+
+	config, err := NewProjectConfig("my-api", "web-api", "stdlib", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Vars = map[string]any{"cors": true, "metrics": true}
+	plan, err := DryRun(config)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+
+	// The embedded template is indented with spaces; the output is gofmt'ed
+	for _, entry := range plan.Files() {
+		if !strings.HasSuffix(entry.Path, ".go") {
+			continue
+		}
+		if issues := checkSource(entry.Path, entry.content); len(issues) > 0 {
+			t.Errorf("%s", issues[0])
+		}
+	}
+}
+
+func TestGenerateKeepsFormatErrors(t *testing.T) {
+	isolateTemplateDirs(t)
+	templateDir := t.TempDir()
+	writeTemplate(t, templateDir, "worker", "stdlib", map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\tx :=\n}\n",
+	})
+
+	config, err := NewProjectConfig("my-worker", "worker", "stdlib", filepath.Join(t.TempDir(), "svc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Template = templateDir
+	plan, err := DryRun(config)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if files := plan.Files(); len(files) != 1 || !strings.Contains(files[0].FormatError, "expected operand") {
+		t.Errorf("Expected the format error in the plan, got %+v", files)
+	}
+
+	// The file is written as rendered, and the plan says why
+	written, err := Generate(config)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if errs := written.FormatErrors(); len(errs) != 1 || !strings.HasPrefix(errs[0], "main.go was written unformatted: failed to format Go source: 5:1: expected operand") {
+		t.Errorf("Expected the format error of main.go, got %q", errs)
+	}
+}
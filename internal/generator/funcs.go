@@ -0,0 +1,218 @@
+package generator
+
+import (
+	"crypto/rand"
+	"fmt"
+	"go/token"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// templateFuncs returns the helpers available to file contents and path
+// templates, in addition to the text/template builtins:
+//
+//	snake "MyAPI server"      -> "my_api_server"
+//	camel "my-api"            -> "myApi"
+//	pascal "my-api"           -> "MyApi"
+//	kebab "MyAPIServer"       -> "my-api-server"
+//	goIdent "2nd-api"         -> "_2ndApi" (valid Go identifier, keywords get a "_" suffix)
+//	plural "category"         -> "categories"
+//	quote "a\"b"              -> "\"a\\\"b\"" (Go string literal)
+//	default "8080" .Vars.port -> .Vars.port, or "8080" if it is empty
+//	indent 4 "a\nb"           -> "    a\n    b"
+//	year                      -> 2026
+//	date "2006-01-02"         -> current date in the given Go layout
+//	uuid                      -> random RFC 4122 version 4 UUID
+//
+// The time and randomness come from config.Clock and config.Random.
+func templateFuncs(config ProjectConfig) template.FuncMap {
+	clock := config.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	random := config.Random
+	if random == nil {
+		random = rand.Reader
+	}
+
+	return template.FuncMap{
+		"snake":   toSnake,
+		"camel":   toCamel,
+		"pascal":  toPascal,
+		"kebab":   toKebab,
+		"goIdent": goIdent,
+		"plural":  plural,
+		"quote":   strconv.Quote,
+		"default": defaultValue,
+		"indent":  indent,
+		"year":    func() int { return clock().Year() },
+		"date":    func(layout string) string { return clock().Format(layout) },
+		"uuid":    func() (string, error) { return newUUID(random) },
+	}
+}
+
+// splitWords splits s into words at separators and case changes. Digits
+// belong to the word they are in ("v2api", "2nd"); only an upper case letter
+// after a digit starts a new word ("v2Api" -> "v2", "Api").
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+
+		if len(current) > 0 {
+			prev := current[len(current)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			switch {
+			// "myApi" -> "my", "Api"
+			case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+				flush()
+			// "HTTPServer" -> "HTTP", "Server"
+			case unicode.IsUpper(r) && unicode.IsUpper(prev) && nextLower:
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+
+	return words
+}
+
+// toSnake converts s to snake_case
+func toSnake(s string) string {
+	return strings.ToLower(strings.Join(splitWords(s), "_"))
+}
+
+// toKebab converts s to kebab-case
+func toKebab(s string) string {
+	return strings.ToLower(strings.Join(splitWords(s), "-"))
+}
+
+// toPascal converts s to PascalCase
+func toPascal(s string) string {
+	var b strings.Builder
+	for _, word := range splitWords(s) {
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
+
+// toCamel converts s to camelCase
+func toCamel(s string) string {
+	pascal := []rune(toPascal(s))
+	if len(pascal) > 0 {
+		pascal[0] = unicode.ToLower(pascal[0])
+	}
+	return string(pascal)
+}
+
+// goIdent converts s to a valid, unexported style Go identifier
+func goIdent(s string) string {
+	ident := toCamel(s)
+	if ident == "" {
+		return "_"
+	}
+	if unicode.IsDigit([]rune(ident)[0]) {
+		ident = "_" + ident
+	}
+	if token.IsKeyword(ident) {
+		ident += "_"
+	}
+	return ident
+}
+
+// irregularPlurals lists common English nouns without a regular plural
+var irregularPlurals = map[string]string{
+	"child":  "children",
+	"person": "people",
+	"man":    "men",
+	"woman":  "women",
+	"mouse":  "mice",
+	"datum":  "data",
+	"index":  "indices",
+}
+
+// plural returns the English plural of a singular noun
+func plural(s string) string {
+	lower := strings.ToLower(s)
+	if p, ok := irregularPlurals[lower]; ok {
+		// Keep the capitalisation of the first letter
+		if s != lower {
+			return strings.ToUpper(p[:1]) + p[1:]
+		}
+		return p
+	}
+
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return s + "es"
+	default:
+		return s + "s"
+	}
+}
+
+// defaultValue returns value, or def if value is empty
+func defaultValue(def, value any) any {
+	if value == nil {
+		return def
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return def
+		}
+	default:
+		if v.IsZero() {
+			return def
+		}
+	}
+	return value
+}
+
+// indent prefixes every non-empty line of s with n spaces
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// newUUID returns a version 4 UUID read from random
+func newUUID(random io.Reader) (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(random, b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate uuid: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
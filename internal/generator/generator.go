@@ -1,13 +1,17 @@
 package generator
 
 import (
+	"context"
 	"embed"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
+	"time"
 )
 
 //go:embed templates/*
@@ -15,55 +19,224 @@ var templateFS embed.FS
 
 // ProjectConfig holds the configuration for project generation
 type ProjectConfig struct {
-	ProjectName   string // e.g., "my-api" or extracted from pwd
-	ModuleName    string // same as ProjectName for now
-	AppType       string // "web-api"
-	Package       string // "stdlib"
-	TargetDir     string // "./my-api/" or "./"
-	UseCurrentDir bool   // true if user entered "."
+	ProjectName   string         `json:"projectName,omitempty" yaml:"projectName,omitempty"`     // e.g., "my-api" or extracted from pwd
+	ModuleName    string         `json:"moduleName,omitempty" yaml:"moduleName,omitempty"`       // Go module path, e.g. "github.com/ourorg/my-api"
+	AppType       string         `json:"appType,omitempty" yaml:"appType,omitempty"`             // "web-api"
+	Package       string         `json:"package,omitempty" yaml:"package,omitempty"`             // "stdlib"
+	TargetDir     string         `json:"targetDir,omitempty" yaml:"targetDir,omitempty"`         // "./my-api/" or "./"
+	UseCurrentDir bool           `json:"useCurrentDir,omitempty" yaml:"useCurrentDir,omitempty"` // true if user entered "."
+	GoVersion     string         `json:"goVersion,omitempty" yaml:"goVersion,omitempty"`         // defaults to the template's goVersion
+	Vars          map[string]any `json:"vars,omitempty" yaml:"vars,omitempty"`                   // template specific variables
+	Template      string         `json:"template,omitempty" yaml:"template,omitempty"`           // extra template directory, searched first
+	PostGenerate  []string       `json:"postGenerate,omitempty" yaml:"postGenerate,omitempty"`   // post-generation steps to run; nil runs none
+	Git           GitOptions     `json:"git,omitzero" yaml:"git,omitempty"`                      // git repository created after the post-generation steps
+	Verify        VerifyLevel    `json:"verify,omitempty" yaml:"verify,omitempty"`               // checks of the generated code after the post-generation steps
+
+	// OnConflict decides what happens to files that already exist; defaults to ConflictAbort
+	OnConflict ConflictPolicy `json:"onConflict,omitempty" yaml:"onConflict,omitempty"`
+	// ConflictResolutions overrides OnConflict for individual files, keyed by planned path
+	ConflictResolutions map[string]ConflictPolicy `json:"-" yaml:"-"`
+
+	// Clock and Random back the year, date and uuid helpers; they default to
+	// time.Now and crypto/rand. Snapshot tests fix them to render reproducibly.
+	Clock  func() time.Time `json:"-" yaml:"-"`
+	Random io.Reader        `json:"-" yaml:"-"`
 }
 
-// Generate is the main orchestration function for project generation
-func Generate(config ProjectConfig) error {
-	// Create target directory if not using current dir
-	if !config.UseCurrentDir {
-		if err := createDirectory(config.TargetDir); err != nil {
-			return fmt.Errorf("failed to create target directory: %w", err)
+// validProjectName matches names made of letters, numbers, hyphens and underscores
+var validProjectName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ValidateProjectName validates a project name as entered by the user
+func ValidateProjectName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("project name cannot be empty")
+	}
+
+	// Allow "." as special case for current directory
+	if name == "." {
+		return nil
+	}
+
+	// Validate project name format (alphanumeric, hyphens, underscores)
+	if !validProjectName.MatchString(name) {
+		return fmt.Errorf("project name must contain only letters, numbers, hyphens, and underscores")
+	}
+
+	return nil
+}
+
+// NewProjectConfig builds a ProjectConfig from user supplied values.
+// A name of "." generates into the current directory and takes the project
+// name from it. An empty targetDir defaults to "./{name}/". The module path
+// defaults to DefaultModulePath.
+func NewProjectConfig(name, appType, packageName, targetDir string) (ProjectConfig, error) {
+	if err := ValidateProjectName(name); err != nil {
+		return ProjectConfig{}, err
+	}
+
+	config := ProjectConfig{
+		ProjectName: name,
+		AppType:     appType,
+		Package:     packageName,
+		TargetDir:   targetDir,
+	}
+
+	if name == "." {
+		// Use current directory name as project name
+		currentDir, err := GetCurrentDirName()
+		if err != nil {
+			return ProjectConfig{}, fmt.Errorf("failed to get current directory name: %w", err)
 		}
+		config.ProjectName = currentDir
+		if targetDir == "" {
+			config.TargetDir = "./"
+			config.UseCurrentDir = true
+		}
+	} else if targetDir == "" {
+		config.TargetDir = fmt.Sprintf("./%s/", name)
 	}
 
-	// Get the embedded template filesystem for the config
-	templateFS, err := getTemplateFS(config.AppType, config.Package)
+	// An explicit target of the current directory behaves like "."
+	if targetDir != "" && filepath.Clean(targetDir) == "." {
+		config.UseCurrentDir = true
+	}
+
+	config.ModuleName = DefaultModulePath(config.ProjectName, config.TargetDir)
+
+	return config, nil
+}
+
+// Validate checks that the config is complete and refers to an existing template
+func (c ProjectConfig) Validate() error {
+	if err := ValidateProjectName(c.ProjectName); err != nil {
+		return err
+	}
+	if c.ModuleName != "" {
+		if err := ValidateModulePath(c.ModuleName); err != nil {
+			return err
+		}
+	}
+	if c.AppType == "" {
+		return fmt.Errorf("application type is required")
+	}
+	if c.Package == "" {
+		return fmt.Errorf("package is required")
+	}
+	if c.TargetDir == "" {
+		return fmt.Errorf("target directory is required")
+	}
+	if c.OnConflict != "" {
+		if _, err := ParseConflictPolicy(string(c.OnConflict)); err != nil {
+			return err
+		}
+	}
+	tmpl, err := FindTemplateIn(c.Template, c.AppType, c.Package)
 	if err != nil {
-		return fmt.Errorf("failed to get template filesystem: %w", err)
+		return err
 	}
+	if _, err := tmpl.apply(c); err != nil {
+		return err
+	}
+	if _, err := tmpl.SelectPostSteps(c); err != nil {
+		return err
+	}
+	if err := c.Git.Validate(); err != nil {
+		return err
+	}
+	if c.Verify != "" {
+		if _, err := ParseVerifyLevel(string(c.Verify)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Walk through template files and copy them
-	if err := copyTemplateFiles(templateFS, config.TargetDir, config); err != nil {
-		return fmt.Errorf("failed to copy template files: %w", err)
+// Generate is the main orchestration function for project generation. It
+// returns the plan it wrote, which records the Go files that could not be
+// formatted.
+func Generate(config ProjectConfig) (*Plan, error) {
+	return GenerateContext(context.Background(), config)
+}
+
+// GenerateContext is like Generate; cancelling ctx stops writing the files
+// and undoes what was written so far
+func GenerateContext(ctx context.Context, config ProjectConfig) (*Plan, error) {
+	// Render the whole template in memory before touching the disk
+	plan, err := DryRun(config)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	// Check for existing files before anything is written
+	if err := plan.resolveConflicts(config); err != nil {
+		return nil, err
+	}
+
+	// Record how the project was generated next to the files, with a copy
+	// of the output for update to merge against
+	if err := plan.addLockfile(); err != nil {
+		return nil, err
+	}
+	plan.addBase()
+
+	// Stage the files and move them into place, removing partial output on error
+	if err := plan.commit(ctx, config.TargetDir); err != nil {
+		return nil, err
+	}
+
+	// Cached templates that are in use are kept by prune
+	plan.template.markUsed()
+
+	return plan, nil
 }
 
-// getTemplateFS returns the embedded filesystem for specific template
-func getTemplateFS(appType, packageName string) (fs.FS, error) {
-	// Template path format: "templates/{appType}-{packageName}"
-	// Example: "templates/web-api-stdlib"
-	templatePath := fmt.Sprintf("templates/%s-%s", appType, packageName)
+// DryRun renders the template for config and returns the resulting plan
+// without writing anything to disk
+func DryRun(config ProjectConfig) (*Plan, error) {
+	// A new project whose parent is inside a git work tree belongs to that
+	// repository. The lockfile records this, so that later renders of the
+	// project plan the same files.
+	config.Git.Nested = config.Git.Init && nestedInWorkTree(config.TargetDir)
+	return render(config)
+}
 
-	templateSubFS, err := fs.Sub(templateFS, templatePath)
+// render renders the template for config into a plan, as recorded in a
+// lockfile
+func render(config ProjectConfig) (*Plan, error) {
+	// Find the template declared for the app type and package
+	tmpl, err := FindTemplateIn(config.Template, config.AppType, config.Package)
 	if err != nil {
-		return nil, fmt.Errorf("template not found: %s (available templates: check templates/ directory)", templatePath)
+		return nil, fmt.Errorf("failed to get template filesystem: %w", err)
+	}
+
+	// Refuse cached templates whose files changed since they were added
+	if err := tmpl.verify(); err != nil {
+		return nil, err
 	}
 
-	// Verify the template exists by checking if we can read at least one file
-	_, err = fs.ReadDir(templateSubFS, ".")
+	// Fill in template defaults and check the variables
+	config, err = tmpl.apply(config)
 	if err != nil {
-		return nil, fmt.Errorf("template directory is empty or invalid: %s", templatePath)
+		return nil, err
 	}
 
-	return templateSubFS, nil
+	// Walk through template files and render them into the plan
+	plan := &Plan{TargetDir: config.TargetDir, template: tmpl, config: config}
+	if err := copyTemplateFiles(tmpl, plan, config); err != nil {
+		return nil, fmt.Errorf("failed to copy template files: %w", err)
+	}
+
+	// A new repository gets the template's .gitignore; a project inside an
+	// existing work tree gets no repository, so it keeps that one's ignores
+	if config.Git.Init && !config.Git.Nested && !plan.has(".gitignore") {
+		gitignore, err := tmpl.renderGitignore(config)
+		if err != nil {
+			return nil, err
+		}
+		plan.addFile(".gitignore", ManifestFile, []byte(gitignore))
+	}
+
+	return plan, nil
 }
 
 // createDirectory creates directory and all parent directories
@@ -81,35 +254,51 @@ func createDirectory(path string) error {
 	return nil
 }
 
-// copyTemplateFiles walks through all files in templateFS and copies them
-func copyTemplateFiles(templateFS fs.FS, targetDir string, config ProjectConfig) error {
-	return fs.WalkDir(templateFS, ".", func(path string, d fs.DirEntry, err error) error {
+// copyTemplateFiles walks through all files of the template and adds the ones
+// selected by config to the plan
+func copyTemplateFiles(tmpl *Template, plan *Plan, config ProjectConfig) error {
+	return fs.WalkDir(tmpl.FS, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Skip the root directory
-		if path == "." {
+		// Skip the root directory and the template manifest
+		if path == "." || path == ManifestFile {
 			return nil
 		}
 
-		// Construct the full target path
-		targetPath := filepath.Join(targetDir, path)
+		// Skip paths excluded by the manifest rules or by an empty path segment
+		included, err := tmpl.included(path, config)
+		if err != nil {
+			return err
+		}
+		targetPath, ok, err := renderPath(path, config)
+		if err != nil {
+			return err
+		}
+		if !included || !ok {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
 
-		// If it's a directory, create it
+		// If it's a directory, plan to create it
 		if d.IsDir() {
-			return createDirectory(targetPath)
+			plan.addDir(targetPath, path)
+			return nil
 		}
 
 		// Handle files
-		return copyFile(templateFS, path, targetPath, config)
+		return copyFile(tmpl, path, targetPath, plan, config)
 	})
 }
 
-// copyFile copies a single file from template to target, processing templates if needed
-func copyFile(templateFS fs.FS, sourcePath, targetPath string, config ProjectConfig) error {
+// copyFile renders a single file from the template into the plan, processing
+// templates if needed and formatting Go files
+func copyFile(tmpl *Template, sourcePath, targetPath string, plan *Plan, config ProjectConfig) error {
 	// Read the source file
-	sourceContent, err := fs.ReadFile(templateFS, sourcePath)
+	sourceContent, err := fs.ReadFile(tmpl.FS, sourcePath)
 	if err != nil {
 		return fmt.Errorf("failed to read source file %s: %w", sourcePath, err)
 	}
@@ -133,18 +322,26 @@ func copyFile(templateFS fs.FS, sourcePath, targetPath string, config ProjectCon
 		finalPath = targetPath
 	}
 
-	// Write the target file
-	if err := os.WriteFile(finalPath, []byte(finalContent), 0644); err != nil {
-		return fmt.Errorf("failed to write target file %s: %w", finalPath, err)
+	// Format Go files unless the manifest opts out; code that cannot be
+	// formatted is kept as rendered, with the error for verification and
+	// lint to report
+	var formatErr error
+	if strings.HasSuffix(finalPath, ".go") && tmpl.formatted(sourcePath) {
+		formatted, err := formatGo([]byte(finalContent), config.ModuleName)
+		if err == nil {
+			finalContent = string(formatted)
+		}
+		formatErr = err
 	}
 
+	plan.addRenderedFile(finalPath, sourcePath, []byte(finalContent), formatErr)
 	return nil
 }
 
 // processTemplate uses text/template to replace variables
 func processTemplate(templateContent string, config ProjectConfig) (string, error) {
 	// Create and parse the template
-	tmpl, err := template.New("project").Parse(templateContent)
+	tmpl, err := template.New("project").Funcs(templateFuncs(config)).Parse(templateContent)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
@@ -158,6 +355,12 @@ func processTemplate(templateContent string, config ProjectConfig) (string, erro
 	return result.String(), nil
 }
 
+// newTemplate creates an empty text/template with the helper functions, for
+// checking templates that are parsed but not executed
+func newTemplate(name string) *template.Template {
+	return template.New(name).Funcs(templateFuncs(ProjectConfig{}))
+}
+
 // GetCurrentDirName gets the current working directory name (not full path)
 func GetCurrentDirName() (string, error) {
 	// Get current working directory
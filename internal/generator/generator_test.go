@@ -4,8 +4,11 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetCurrentDirName(t *testing.T) {
@@ -64,15 +67,20 @@ func TestProcessTemplate(t *testing.T) {
 	}
 }
 
-func TestGetTemplateFS(t *testing.T) {
+func TestFindTemplateInEmbedded(t *testing.T) {
+	isolateTemplateDirs(t)
+
 	// Test with valid template
-	templateFS, err := getTemplateFS("web-api", "stdlib")
+	tmpl, err := FindTemplateIn("", "web-api", "stdlib")
 	if err != nil {
-		t.Fatalf("getTemplateFS failed: %v", err)
+		t.Fatalf("FindTemplateIn failed: %v", err)
+	}
+	if tmpl.Source != SourceEmbedded {
+		t.Errorf("Expected the embedded template, got %s", tmpl.Source)
 	}
 
 	// Verify we can read files
-	files, err := fs.ReadDir(templateFS, ".")
+	files, err := fs.ReadDir(tmpl.FS, ".")
 	if err != nil {
 		t.Fatalf("Failed to read template directory: %v", err)
 	}
@@ -82,9 +90,9 @@ func TestGetTemplateFS(t *testing.T) {
 	}
 
 	// Test with invalid template
-	_, err = getTemplateFS("invalid", "invalid")
+	_, err = FindTemplateIn("", "invalid", "invalid")
 	if err == nil {
-		t.Error("getTemplateFS should have failed with invalid template")
+		t.Error("FindTemplateIn should have failed with invalid template")
 	}
 }
 
@@ -101,7 +109,7 @@ func TestGenerate(t *testing.T) {
 		UseCurrentDir: false,
 	}
 
-	err := Generate(config)
+	_, err := Generate(config)
 	if err != nil {
 		t.Fatalf("Generate failed: %v", err)
 	}
@@ -112,6 +120,11 @@ func TestGenerate(t *testing.T) {
 		t.Error("go.mod file was not created")
 	}
 
+	mainPath := filepath.Join(testDir, "cmd", "test-project", "main.go")
+	if _, err := os.Stat(mainPath); os.IsNotExist(err) {
+		t.Error("cmd/test-project/main.go file was not created")
+	}
+
 	testTxtPath := filepath.Join(testDir, "test.txt")
 	if _, err := os.Stat(testTxtPath); os.IsNotExist(err) {
 		t.Error("test.txt file was not created")
@@ -128,3 +141,175 @@ func TestGenerate(t *testing.T) {
 		t.Errorf("go.mod content mismatch. Got: %s, Expected to contain: %s", string(content), expectedContent)
 	}
 }
+
+func TestNewProjectConfig(t *testing.T) {
+	config, err := NewProjectConfig("my-api", "web-api", "stdlib", "")
+	if err != nil {
+		t.Fatalf("NewProjectConfig failed: %v", err)
+	}
+	if config.TargetDir != "./my-api/" || config.UseCurrentDir {
+		t.Errorf("Unexpected target for named project: %+v", config)
+	}
+
+	config, err = NewProjectConfig(".", "web-api", "stdlib", "")
+	if err != nil {
+		t.Fatalf("NewProjectConfig failed: %v", err)
+	}
+	if config.TargetDir != "./" || !config.UseCurrentDir || config.ProjectName == "." {
+		t.Errorf("Unexpected target for current directory: %+v", config)
+	}
+
+	if _, err := NewProjectConfig("my project", "web-api", "stdlib", ""); err == nil {
+		t.Error("NewProjectConfig should have failed with invalid name")
+	}
+}
+
+func TestProjectConfigValidate(t *testing.T) {
+	config := ProjectConfig{ProjectName: "my-api", AppType: "web-api", Package: "stdlib", TargetDir: "./my-api/"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+
+	config.Package = "invalid"
+	if err := config.Validate(); err == nil {
+		t.Error("Validate should have failed with unknown template")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	testDir := filepath.Join(t.TempDir(), "dry-run")
+
+	config := ProjectConfig{
+		ProjectName: "test-project",
+		ModuleName:  "test-project",
+		AppType:     "web-api",
+		Package:     "stdlib",
+		TargetDir:   testDir,
+	}
+
+	plan, err := DryRun(config)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+
+	// Nothing should be written
+	if _, err := os.Stat(testDir); !os.IsNotExist(err) {
+		t.Error("DryRun created the target directory")
+	}
+
+	var goMod *PlanEntry
+	for i, entry := range plan.Entries {
+		if strings.HasSuffix(entry.Path, ".tmpl") {
+			t.Errorf("Plan path still has .tmpl suffix: %s", entry.Path)
+		}
+		if entry.Path == "go.mod" {
+			goMod = &plan.Entries[i]
+		}
+	}
+
+	if goMod == nil {
+		t.Fatal("go.mod missing from plan")
+	}
+	if goMod.Source != "go.mod.tmpl" || goMod.Size != int64(len("module test-project\n\ngo 1.21")) || goMod.Mode != 0644 {
+		t.Errorf("Unexpected go.mod entry: %+v", *goMod)
+	}
+
+	tree := plan.Tree()
+	if !strings.Contains(tree, "├── cmd/") || !strings.Contains(tree, "main.go") {
+		t.Errorf("Tree is missing entries:\n%s", tree)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`{{snake "MyAPI server"}}`, "my_api_server"},
+		{`{{snake "userID"}}`, "user_id"},
+		{`{{camel "my-api"}}`, "myApi"},
+		{`{{camel "HTTPServer"}}`, "httpServer"},
+		{`{{pascal "my_api v2"}}`, "MyApiV2"},
+		{`{{kebab "MyAPIServer"}}`, "my-api-server"},
+		{`{{snake "v2api"}}`, "v2api"},
+		{`{{snake "v2Api"}}`, "v2_api"},
+		{`{{snake "OAuth2Client"}}`, "o_auth2_client"},
+		{`{{goIdent "my-api"}}`, "myApi"},
+		{`{{goIdent "2nd-api"}}`, "_2ndApi"},
+		{`{{goIdent "type"}}`, "type_"},
+		{`{{goIdent "--"}}`, "_"},
+		{`{{plural "user"}}`, "users"},
+		{`{{plural "category"}}`, "categories"},
+		{`{{plural "key"}}`, "keys"},
+		{`{{plural "box"}}`, "boxes"},
+		{`{{plural "Person"}}`, "People"},
+		{`{{quote .ProjectName}}`, `"test-project"`},
+		{`{{quote "a\"b"}}`, `"a\"b"`},
+		{`{{default "none" .Vars.missing}}`, "none"},
+		{`{{default 8080 .Vars.empty}}`, "8080"},
+		{`{{default 8080 .Vars.port}}`, "9090"},
+		{`{{indent 2 "a\n\nb"}}`, "  a\n\n  b"},
+		{`{{.ProjectName | pascal}}`, "TestProject"},
+	}
+
+	config := ProjectConfig{
+		ProjectName: "test-project",
+		Vars:        map[string]any{"port": 9090, "empty": ""},
+	}
+	for _, tt := range tests {
+		got, err := processTemplate(tt.template, config)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.template, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateFuncsDateAndUUID(t *testing.T) {
+	got, err := processTemplate(`{{year}} {{date "2006"}}`, ProjectConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	year := strconv.Itoa(time.Now().Year())
+	if got != year+" "+year {
+		t.Errorf("year and date = %q, want %q", got, year+" "+year)
+	}
+
+	first, err := processTemplate("{{uuid}}", ProjectConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := processTemplate("{{uuid}}", ProjectConfig{})
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(first) {
+		t.Errorf("uuid = %q, want a version 4 UUID", first)
+	}
+	if first == second {
+		t.Errorf("uuid returned %q twice", first)
+	}
+
+	// The config fixes the time and randomness
+	config := ProjectConfig{
+		Clock:  func() time.Time { return time.Date(2000, time.March, 4, 0, 0, 0, 0, time.UTC) },
+		Random: strings.NewReader(strings.Repeat("\x00", 16)),
+	}
+	got, err = processTemplate(`{{year}} {{date "01-02"}} {{uuid}}`, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "2000 03-04 00000000-0000-4000-8000-000000000000"; got != want {
+		t.Errorf("with a fixed clock and random source = %q, want %q", got, want)
+	}
+}
+
+func TestTemplateFuncsInPaths(t *testing.T) {
+	got, ok, err := renderPath("internal/{{snake .ProjectName}}/{{plural \"handler\"}}.go", ProjectConfig{ProjectName: "MyApp"})
+	if err != nil || !ok {
+		t.Fatalf("renderPath() = %q, %v, %v", got, ok, err)
+	}
+	if got != "internal/my_app/handlers.go" {
+		t.Errorf("renderPath() = %q, want %q", got, "internal/my_app/handlers.go")
+	}
+}
@@ -0,0 +1,174 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/manuelbamise/go-ten/internal/commands"
+)
+
+// gitTimeout limits each git command run for a new project
+const gitTimeout = time.Minute
+
+// GitCommitMessage is the message of the first commit of a new project
+const GitCommitMessage = "Initial commit"
+
+// defaultGitignore is written to projects whose template has no gitignore
+const defaultGitignore = `# Binaries
+/{{.ProjectName}}
+*.exe
+*.dll
+*.so
+*.dylib
+
+# Test and coverage output
+*.test
+*.out
+coverage.*
+
+# Local environment
+.env
+`
+
+// gitignoreWorkDirs is appended to every generated .gitignore, in case an
+// interrupted run left its work directories in the project
+const gitignoreWorkDirs = `
+# go-ten work directories
+/` + stagingPattern + `/
+/` + backupPattern + `/
+`
+
+// GitOptions configures the git repository created for a new project
+type GitOptions struct {
+	Init   bool   `json:"init,omitempty" yaml:"init,omitempty"`     // create a repository with an initial commit
+	Branch string `json:"branch,omitempty" yaml:"branch,omitempty"` // initial branch; git's init.defaultBranch when empty
+	Author string `json:"author,omitempty" yaml:"author,omitempty"` // "Name <email>" of the initial commit; git's user.name and user.email when empty
+
+	// Nested is set by DryRun when the parent of the project is inside a git
+	// work tree; the project then gets no repository and no .gitignore
+	Nested bool `json:"nested,omitempty" yaml:"-"`
+}
+
+// Validate checks the branch name and author
+func (o GitOptions) Validate() error {
+	if b := o.Branch; b != "" {
+		if strings.HasPrefix(b, "-") || strings.HasPrefix(b, "/") || strings.HasSuffix(b, "/") ||
+			strings.HasSuffix(b, ".lock") || strings.Contains(b, "..") || strings.ContainsAny(b, " \t~^:?*[\\") {
+			return fmt.Errorf("invalid git branch name %q", b)
+		}
+	}
+	if o.Author != "" {
+		if _, err := ParseGitAuthor(o.Author); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseGitAuthor parses an author given as "Name <email>"
+func ParseGitAuthor(author string) (*mail.Address, error) {
+	addr, err := mail.ParseAddress(author)
+	if err != nil || addr.Name == "" {
+		return nil, fmt.Errorf("invalid git author %q, expected \"Name <email>\"", author)
+	}
+	return addr, nil
+}
+
+// renderGitignore returns the .gitignore of the template rendered for config
+func (t *Template) renderGitignore(config ProjectConfig) (string, error) {
+	gitignore := t.Gitignore
+	if gitignore == "" {
+		gitignore = defaultGitignore
+	}
+	content, err := processTemplate(gitignore, config)
+	if err != nil {
+		return "", fmt.Errorf("failed to render gitignore: %w", err)
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + gitignoreWorkDirs, nil
+}
+
+// InsideGitWorkTree reports whether dir is inside a git work tree. A dir
+// that does not exist yet is checked at its closest existing parent.
+func InsideGitWorkTree(ctx context.Context, dir string) (bool, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	result, err := commands.Run(ctx, commands.Command{Name: "git", Args: []string{"rev-parse", "--is-inside-work-tree"}, Dir: closestExisting(abs), Timeout: gitTimeout})
+	if result == nil {
+		// git could not be started
+		return false, fmt.Errorf("failed to run git: %w", err)
+	}
+	return err == nil && strings.TrimSpace(result.Stdout) == "true", nil
+}
+
+// nestedInWorkTree reports whether the parent of the project directory dir
+// is inside a git work tree. For planning, a missing git counts as no work
+// tree; InitGit reports it later.
+func nestedInWorkTree(dir string) bool {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	inside, _ := InsideGitWorkTree(context.Background(), filepath.Dir(abs))
+	return inside
+}
+
+// InitGit creates a git repository in dir and commits everything in it,
+// unless dir already is inside a git work tree. It reports whether a
+// repository was created; git's output goes to out.
+func InitGit(ctx context.Context, dir string, opts GitOptions, out io.Writer) (bool, error) {
+	// A project inside another repository belongs to it
+	inside, err := InsideGitWorkTree(ctx, dir)
+	if err != nil {
+		return false, err
+	}
+	if inside {
+		fmt.Fprintf(out, "%s is already inside a git work tree, skipping git init\n", dir)
+		return false, nil
+	}
+
+	var env []string
+	if opts.Author != "" {
+		author, err := ParseGitAuthor(opts.Author)
+		if err != nil {
+			return false, err
+		}
+		env = []string{
+			"GIT_AUTHOR_NAME=" + author.Name, "GIT_AUTHOR_EMAIL=" + author.Address,
+			"GIT_COMMITTER_NAME=" + author.Name, "GIT_COMMITTER_EMAIL=" + author.Address,
+		}
+	}
+
+	git := func(args ...string) error {
+		cmd := commands.Command{Name: "git", Args: args, Dir: dir, Env: env, Timeout: gitTimeout, Stdout: out, Stderr: out}
+		fmt.Fprintf(out, "$ %s\n", cmd)
+		_, err := commands.Run(ctx, cmd)
+		return err
+	}
+
+	if err := git("init"); err != nil {
+		return false, err
+	}
+	// symbolic-ref works with every git version, unlike init --initial-branch
+	if opts.Branch != "" {
+		if err := git("symbolic-ref", "HEAD", "refs/heads/"+opts.Branch); err != nil {
+			return true, err
+		}
+	}
+	if err := git("add", "-A"); err != nil {
+		return true, err
+	}
+	if err := git("commit", "-q", "-m", GitCommitMessage); err != nil {
+		return true, err
+	}
+	return true, nil
+}
@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitOutput runs git in dir and returns its trimmed output
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestInitGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	isolateTemplateDirs(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	// The .gitignore is part of the generated files
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	config, err := NewProjectConfig("my-api", "web-api", "stdlib", targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Git = GitOptions{Init: true, Branch: "trunk", Author: "Jane Doe <jane@example.com>"}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	gitignore, err := os.ReadFile(filepath.Join(targetDir, ".gitignore"))
+	if err != nil || !strings.Contains(string(gitignore), "/my-api\n") {
+		t.Errorf("Expected a .gitignore ignoring the binary, got %q (%v)", gitignore, err)
+	}
+
+	var out bytes.Buffer
+	created, err := InitGit(context.Background(), targetDir, config.Git, &out)
+	if err != nil || !created {
+		t.Fatalf("InitGit failed: %v\n%s", err, out.String())
+	}
+	if branch := gitOutput(t, targetDir, "symbolic-ref", "--short", "HEAD"); branch != "trunk" {
+		t.Errorf("Expected branch trunk, got %s", branch)
+	}
+	if author := gitOutput(t, targetDir, "log", "-1", "--format=%an <%ae>|%s"); author != "Jane Doe <jane@example.com>|"+GitCommitMessage {
+		t.Errorf("Unexpected initial commit: %s", author)
+	}
+	if status := gitOutput(t, targetDir, "status", "--porcelain"); status != "" {
+		t.Errorf("Everything should be committed:\n%s", status)
+	}
+	if files := gitOutput(t, targetDir, "ls-files"); !strings.Contains(files, ".gitignore") || !strings.Contains(files, LockFile) {
+		t.Errorf("Expected .gitignore and the lockfile to be committed:\n%s", files)
+	}
+
+	// A project inside a work tree is left to it
+	nested := filepath.Join(targetDir, "tools")
+	if err := os.Mkdir(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	created, err = InitGit(context.Background(), nested, config.Git, &out)
+	if err != nil || created || !strings.Contains(out.String(), "already inside a git work tree") {
+		t.Errorf("Expected git init to be skipped, got created=%v err=%v\n%s", created, err, out.String())
+	}
+	if _, err := os.Stat(filepath.Join(nested, ".git")); !os.IsNotExist(err) {
+		t.Error("No repository should be created inside a work tree")
+	}
+
+	// and gets no .gitignore, even before its directory exists
+	nestedConfig := config
+	nestedConfig.TargetDir = filepath.Join(targetDir, "services", "worker")
+	plan, err := DryRun(nestedConfig)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if plan.has(".gitignore") || !plan.lock().Config.Git.Nested {
+		t.Error("A project inside a work tree should not get a .gitignore, and the lockfile should record it")
+	}
+}
+
+func TestGitOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts  GitOptions
+		valid bool
+	}{
+		{GitOptions{}, true},
+		{GitOptions{Branch: "main", Author: "Jane Doe <jane@example.com>"}, true},
+		{GitOptions{Branch: "release/v1"}, true},
+		{GitOptions{Branch: "-main"}, false},
+		{GitOptions{Branch: "my branch"}, false},
+		{GitOptions{Branch: "a..b"}, false},
+		{GitOptions{Author: "jane@example.com"}, false},
+		{GitOptions{Author: "Jane Doe"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %v", tt.opts, err, tt.valid)
+		}
+	}
+}
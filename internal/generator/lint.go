@@ -0,0 +1,463 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Lint checks a template for problems that otherwise only show up when a
+// project is generated: templates that do not parse, references to fields
+// and variables that do not exist, files that collide once ".tmpl" is
+// stripped, and rendered Go files and go.mod files that do not parse. The
+// template is rendered with sample answers: the variable defaults, and then
+// with every bool and multiselect variable turned on.
+func Lint(tmpl *Template) ([]Issue, error) {
+	l := linter{tmpl: tmpl, seen: map[string]bool{}}
+
+	// Check the sources and their paths
+	err := fs.WalkDir(tmpl.FS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." || name == ManifestFile {
+			return nil
+		}
+		if segment := path.Base(name); strings.Contains(segment, "{{") {
+			l.lintText(name, segment, 0)
+		}
+		if d.IsDir() || !strings.HasSuffix(name, ".tmpl") {
+			return nil
+		}
+		content, err := fs.ReadFile(tmpl.FS, name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		l.lintText(name, string(content), 1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.lintManifest()
+	l.lintCollisions()
+
+	// Render with the sample answers
+	for _, vars := range []map[string]any{l.sampleVars(false), l.sampleVars(true)} {
+		l.lintRender(vars)
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i], l.issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.issues, nil
+}
+
+// linter collects the issues of one template
+type linter struct {
+	tmpl   *Template
+	issues []Issue
+	seen   map[string]bool // issues reported already, by String
+}
+
+// report records an issue once
+func (l *linter) report(issue Issue) {
+	if key := issue.String(); !l.seen[key] {
+		l.seen[key] = true
+		l.issues = append(l.issues, issue)
+	}
+}
+
+// reported reports whether file has issues already; rendering it again
+// would only repeat them
+func (l *linter) reported(file string) bool {
+	for _, issue := range l.issues {
+		if issue.File == file {
+			return true
+		}
+	}
+	return false
+}
+
+// templateError matches the position in text/template errors, e.g.
+// `template: main.go.tmpl:5:12: executing "main.go.tmpl" at <.Vars.x>: ...`
+var templateError = regexp.MustCompile(`^template: .*?:(\d+)(?::(\d+))?: (.*)$`)
+
+// templateIssue converts a text/template error of file into an issue.
+// firstLine is the line of file the template text starts at, or 0 when the
+// text has no position of its own, like a path segment.
+func templateIssue(file string, firstLine int, err error) Issue {
+	issue := Issue{File: file, Message: err.Error()}
+	if match := templateError.FindStringSubmatch(err.Error()); match != nil {
+		issue.Message = match[3]
+		if firstLine > 0 {
+			line, _ := strconv.Atoi(match[1])
+			issue.Line = firstLine + line - 1
+			// text/template counts columns from 0
+			if match[2] != "" {
+				column, _ := strconv.Atoi(match[2])
+				issue.Column = column + 1
+			}
+		}
+	}
+	return issue
+}
+
+// lintText parses template text from file and checks its field references
+func (l *linter) lintText(file, text string, firstLine int) {
+	t, err := newTemplate(file).Parse(text)
+	if err != nil {
+		l.report(templateIssue(file, firstLine, err))
+		return
+	}
+	if t.Tree == nil {
+		return
+	}
+
+	check := fieldChecker{linter: l, file: file, text: text, firstLine: firstLine}
+	check.walk(t.Tree.Root, true)
+}
+
+// lintManifest checks the field references of the templated manifest values
+func (l *linter) lintManifest() {
+	for i, step := range l.tmpl.NextSteps {
+		l.lintText(fmt.Sprintf("%s: nextSteps[%d]", ManifestFile, i), step, 0)
+	}
+	for _, step := range l.tmpl.PostGenerate {
+		if step.Run != "" {
+			l.lintText(fmt.Sprintf("%s: postGenerate %s", ManifestFile, step.Name), step.Run, 0)
+		}
+	}
+	for i, rule := range l.tmpl.Files {
+		for _, cond := range []string{rule.Include, rule.Exclude} {
+			if cond != "" {
+				l.lintText(fmt.Sprintf("%s: files[%d]", ManifestFile, i), conditionTemplate(cond), 0)
+			}
+		}
+	}
+	if l.tmpl.Gitignore != "" {
+		l.lintText(ManifestFile+": gitignore", l.tmpl.Gitignore, 0)
+	}
+}
+
+// lintCollisions reports template files that end up at the same path once
+// the ".tmpl" suffix is stripped, e.g. main.go and main.go.tmpl
+func (l *linter) lintCollisions() {
+	sources := map[string][]string{}
+	fs.WalkDir(l.tmpl.FS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || name == ManifestFile {
+			return nil
+		}
+		target := strings.TrimSuffix(name, ".tmpl")
+		sources[target] = append(sources[target], name)
+		return nil
+	})
+	for target, names := range sources {
+		if len(names) > 1 {
+			sort.Strings(names)
+			l.report(Issue{File: names[0], Message: fmt.Sprintf("%s and %s both generate %s", names[0], strings.Join(names[1:], ", "), target)})
+		}
+	}
+}
+
+// sampleVars returns answers for every variable: the defaults, or sample
+// values where there is none. With all set, bool variables are true and
+// multiselect variables have every choice selected.
+func (l *linter) sampleVars(all bool) map[string]any {
+	vars := map[string]any{}
+	for _, v := range l.tmpl.Variables {
+		switch {
+		case all && v.Type == VarBool:
+			vars[v.Name] = true
+		case all && v.Type == VarMultiSelect:
+			vars[v.Name] = v.Choices
+		case v.Default != nil:
+			vars[v.Name] = v.Default
+		case len(v.Choices) > 0:
+			vars[v.Name] = v.Choices[0]
+		case v.Type == VarInt:
+			vars[v.Name] = 1
+		case v.Type == VarBool:
+			vars[v.Name] = false
+		default:
+			vars[v.Name] = "sample"
+		}
+	}
+	return vars
+}
+
+// lintRender renders every file of the template with vars and parse-checks
+// the Go and go.mod files it produces
+func (l *linter) lintRender(vars map[string]any) {
+	config := ProjectConfig{
+		ProjectName: "my-project",
+		ModuleName:  "example.com/my-project",
+		AppType:     l.tmpl.AppType,
+		Package:     l.tmpl.Package,
+		TargetDir:   "./my-project/",
+		Vars:        vars,
+	}
+	config, err := l.tmpl.apply(config)
+	if err != nil {
+		l.report(Issue{File: ManifestFile, Message: fmt.Sprintf("sample answers rejected: %v", err)})
+		return
+	}
+
+	targets := map[string]string{}
+	fs.WalkDir(l.tmpl.FS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || name == "." || name == ManifestFile {
+			return nil
+		}
+
+		// Follow the same rules as generation
+		included, err := l.tmpl.included(name, config)
+		if err != nil {
+			l.report(Issue{File: ManifestFile, Message: err.Error()})
+			return nil
+		}
+		target, ok, err := renderPath(name, config)
+		if err != nil {
+			if !l.reported(name) {
+				l.report(Issue{File: name, Message: err.Error()})
+			}
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !included || !ok {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		content, err := fs.ReadFile(l.tmpl.FS, name)
+		if err != nil {
+			l.report(Issue{File: name, Message: err.Error()})
+			return nil
+		}
+		if strings.HasSuffix(name, ".tmpl") {
+			target = strings.TrimSuffix(target, ".tmpl")
+			t, err := newTemplate(name).Parse(string(content))
+			if err != nil {
+				// Reported while parsing already
+				return nil
+			}
+			var out strings.Builder
+			if err := t.Execute(&out, config); err != nil {
+				if !l.reported(name) {
+					l.report(templateIssue(name, 1, err))
+				}
+				return nil
+			}
+			content = []byte(out.String())
+		}
+
+		// Different sources rendering to the same path overwrite each other
+		if other, ok := targets[target]; ok {
+			l.report(Issue{File: name, Message: fmt.Sprintf("generates %s, like %s", target, other)})
+		}
+		targets[target] = name
+
+		l.lintOutput(name, target, content, config.ModuleName)
+		return nil
+	})
+}
+
+// lintOutput parse-checks a rendered file and checks that Go files can be
+// formatted like generation does. Issues are reported on the source file.
+func (l *linter) lintOutput(source, target string, content []byte, module string) {
+	var issues []Issue
+	switch {
+	case strings.HasSuffix(target, ".go"):
+		issues = parseSource(target, content)
+		if len(issues) == 0 && l.tmpl.formatted(source) {
+			if _, err := formatGo(content, module); err != nil {
+				issues = append(issues, Issue{File: target, Message: err.Error()})
+			}
+		}
+
+	case path.Base(target) == "go.mod":
+		if _, err := modfile.Parse(target, content, nil); err != nil {
+			var list modfile.ErrorList
+			if !errors.As(err, &list) {
+				issues = append(issues, Issue{File: target, Message: err.Error()})
+				break
+			}
+			for _, e := range list {
+				issues = append(issues, Issue{File: target, Line: e.Pos.Line, Column: e.Pos.LineRune, Message: e.Err.Error()})
+			}
+		}
+	}
+
+	for _, issue := range issues {
+		l.report(sourceIssue(source, issue))
+	}
+}
+
+// sourceIssue moves an issue found in the output of the template file source
+// onto source. Positions in copied files hold for the source too; those in
+// rendered templates are only named in the message, as output positions.
+func sourceIssue(source string, issue Issue) Issue {
+	if !strings.HasSuffix(source, ".tmpl") {
+		issue.File = source
+		return issue
+	}
+	output := issue
+	output.Message = "rendered output"
+	return Issue{File: source, Message: fmt.Sprintf("%s: %s", output, issue.Message)}
+}
+
+// fieldChecker checks the field references of one parsed template against
+// ProjectConfig and the declared variables
+type fieldChecker struct {
+	*linter
+	file      string
+	text      string
+	firstLine int
+}
+
+// configType is the type of the data templates are executed with
+var configType = reflect.TypeOf(ProjectConfig{})
+
+// walk checks the fields below node. rootDot is false inside range and with
+// blocks, where dot is something other than the ProjectConfig.
+func (c fieldChecker) walk(node parse.Node, rootDot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.walk(child, rootDot)
+		}
+	case *parse.ActionNode:
+		c.walk(n.Pipe, rootDot)
+	case *parse.IfNode:
+		c.walk(n.Pipe, rootDot)
+		c.walk(n.List, rootDot)
+		c.walk(n.ElseList, rootDot)
+	case *parse.RangeNode:
+		c.walk(n.Pipe, rootDot)
+		c.walk(n.List, false)
+		c.walk(n.ElseList, rootDot)
+	case *parse.WithNode:
+		c.walk(n.Pipe, rootDot)
+		c.walk(n.List, false)
+		c.walk(n.ElseList, rootDot)
+	case *parse.TemplateNode:
+		c.walk(n.Pipe, rootDot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			c.walk(cmd, rootDot)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			c.walk(arg, rootDot)
+		}
+	case *parse.ChainNode:
+		c.walk(n.Node, rootDot)
+	case *parse.FieldNode:
+		if rootDot {
+			c.check(n.Position(), n.String(), n.Ident)
+		}
+	case *parse.VariableNode:
+		// $ is the ProjectConfig everywhere
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			c.check(n.Position(), n.String(), n.Ident[1:])
+		}
+	}
+}
+
+// check reports the first field of idents that does not exist. ref is the
+// reference as written, e.g. ".Vars.port", found at or before pos.
+func (c fieldChecker) check(pos parse.Pos, ref string, idents []string) {
+	// The parser places chained fields at their last segment
+	end := min(int(pos)+len(ref), len(c.text))
+	if start := strings.LastIndex(c.text[:end], ref); start >= 0 {
+		pos = parse.Pos(start)
+	}
+
+	t := configType
+	for i, ident := range idents {
+		switch {
+		case i == 1 && idents[0] == "Vars":
+			if !c.declared(ident) {
+				c.reportAt(pos, fmt.Sprintf("unknown variable %q in .Vars.%s (declared: %s)", ident, ident, c.declaredNames()))
+			}
+			return
+		case t.Kind() == reflect.Struct:
+			if _, ok := t.MethodByName(ident); ok {
+				return
+			}
+			field, ok := t.FieldByName(ident)
+			if !ok || !field.IsExported() {
+				c.reportAt(pos, fmt.Sprintf("%s has no field %s in .%s", t.Name(), ident, strings.Join(idents[:i+1], ".")))
+				return
+			}
+			t = field.Type
+		case t.Kind() == reflect.Map || t.Kind() == reflect.Interface:
+			// Keys and dynamic values are only known when rendering
+			return
+		default:
+			c.reportAt(pos, fmt.Sprintf(".%s is a %s and has no field %s", strings.Join(idents[:i], "."), t.Kind(), ident))
+			return
+		}
+	}
+}
+
+// declared reports whether the manifest declares the variable
+func (c fieldChecker) declared(name string) bool {
+	for _, v := range c.tmpl.Variables {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// declaredNames lists the declared variables for messages
+func (c fieldChecker) declaredNames() string {
+	if len(c.tmpl.Variables) == 0 {
+		return "none"
+	}
+	names := make([]string, len(c.tmpl.Variables))
+	for i, v := range c.tmpl.Variables {
+		names[i] = v.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// reportAt reports an issue at a byte offset of the template text
+func (c fieldChecker) reportAt(pos parse.Pos, message string) {
+	issue := Issue{File: c.file, Message: message}
+	if c.firstLine > 0 && int(pos) <= len(c.text) {
+		before := c.text[:pos]
+		issue.Line = c.firstLine + strings.Count(before, "\n")
+		issue.Column = int(pos) - strings.LastIndex(before, "\n")
+	}
+	c.report(issue)
+}
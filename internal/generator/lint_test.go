@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	// The embedded templates are clean
+	templates, err := Templates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range templates {
+		issues, err := Lint(&templates[i])
+		if err != nil || len(issues) > 0 {
+			t.Errorf("Lint(%s) = %v, %v", templates[i].ID(), issues, err)
+		}
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		ManifestFile: `name: Worker
+appType: worker
+package: stdlib
+nextSteps:
+  - go run ./cmd/{{.ProjectNam}}
+variables:
+  - name: queue
+    type: bool
+files:
+  - paths: [queue.go.tmpl]
+    include: .Vars.queue
+`,
+		"go.mod.tmpl": "module {{.ModuleName}}\n\ngo {{.GoVersion}}\n",
+		"main.go.tmpl": "package main\n\n// {{.Modulename}}\nfunc main() {\n" +
+			"{{range .Vars.queues}}{{.Name}}{{end}}\n" +
+			"{{if .Git.Init}}{{$.Git.Branch.Name}}{{end}}\n}\n",
+		"main.go":                     "package main\n",
+		"broken.txt.tmpl":             "line one\n{{if .UseCurrentDir}}\n",
+		"queue.go.tmpl":               "package main\n\nfunc queue() {\n\tfor {\n}\n",
+		"cmd/{{.Name}}/x":             "x\n",
+		"cmd/{{.ProjectName}}/run.go": "package main\n\nfunc run() {\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loaded, err := LoadTemplateDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := Lint(&loaded[0])
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"broken.txt.tmpl:3: unexpected EOF",
+		"cmd/{{.Name}}: ProjectConfig has no field Name in .Name",
+		"cmd/{{.ProjectName}}/run.go:3:14: expected ';', found 'EOF'",
+		"go.mod.tmpl: go.mod:3:1: rendered output: go directive expects exactly one argument",
+		"main.go: main.go and main.go.tmpl both generate main.go",
+		"main.go.tmpl:3:6: ProjectConfig has no field Modulename in .Modulename",
+		"main.go.tmpl:5:9: unknown variable \"queues\" in .Vars.queues (declared: queue)",
+		"main.go.tmpl:6:19: .Git.Branch is a string and has no field Name",
+		"queue.go.tmpl: queue.go:5:3: rendered output: expected ';', found 'EOF'",
+		"template.yaml: nextSteps[0]: ProjectConfig has no field ProjectNam in .ProjectNam",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
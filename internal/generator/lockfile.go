@@ -0,0 +1,183 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+)
+
+// LockFile is written to the root of every generated project and records how
+// it was generated
+const LockFile = ".go-ten.json"
+
+// BaseDir holds a copy of the template output the lockfile records, which
+// update merges local changes against
+const BaseDir = ".go-ten/base"
+
+// Version is the go-ten version recorded in lockfiles. Release builds set it
+// with -ldflags "-X github.com/manuelbamise/go-ten/internal/generator.Version=v1.2.3";
+// otherwise the module version from the build info is used.
+var Version = ""
+
+// Lock is the content of the lockfile
+type Lock struct {
+	GoTenVersion string        `json:"goTenVersion"`
+	GeneratedAt  time.Time     `json:"generatedAt"`
+	Template     LockTemplate  `json:"template"`
+	Config       ProjectConfig `json:"config"` // answers with template defaults applied
+
+	// Files maps every generated file to the hash of its content, "sha256:<hex>"
+	Files map[string]string `json:"files"`
+	// PostStepFiles holds the hashes of generated files as the post-generation
+	// steps left them, for those the steps rewrote, e.g. go.mod after go mod tidy
+	PostStepFiles map[string]string `json:"postStepFiles,omitempty"`
+}
+
+// LockTemplate identifies the template a project was generated from
+type LockTemplate struct {
+	ID      string `json:"id"`     // "{appType}-{package}"
+	Source  string `json:"source"` // SourceEmbedded, a directory or a git source
+	Version string `json:"version,omitempty"`
+	Commit  string `json:"commit,omitempty"` // commit of git sources
+	Digest  string `json:"digest,omitempty"` // digest of cached templates
+}
+
+// ReadLock reads the lockfile of the project in dir
+func ReadLock(dir string) (*Lock, error) {
+	data, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", filepath.Join(dir, LockFile), err)
+	}
+	return &lock, nil
+}
+
+// Generated reports whether content is what generation left at path p: the
+// template output or what the post-generation steps made of it
+func (l *Lock) Generated(p string, content []byte) bool {
+	hash := HashContent(content)
+	return hash == l.Files[p] || hash == l.PostStepFiles[p]
+}
+
+// write replaces the lockfile of the project in dir
+func (l *Lock) write(dir string) error {
+	data, err := l.encode()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, LockFile), data, fileMode); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+// encode returns the lockfile content
+func (l *Lock) encode() ([]byte, error) {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// HashContent returns the hash recorded in lockfiles for file content
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// goTenVersion returns the version of the running go-ten
+func goTenVersion() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+// lock builds the lockfile for the files of the plan
+func (p *Plan) lock() Lock {
+	// The lockfile lives in the target, so where it was generated from does not matter
+	config := p.config
+	config.TargetDir = ""
+	config.UseCurrentDir = false
+	config.OnConflict = ""
+
+	lock := Lock{
+		GoTenVersion: goTenVersion(),
+		GeneratedAt:  time.Now().UTC().Truncate(time.Second),
+		Config:       config,
+		Files:        map[string]string{},
+	}
+	if p.template != nil {
+		lock.Template = LockTemplate{
+			ID:      p.template.ID(),
+			Source:  p.template.Source,
+			Version: p.template.Version,
+			Commit:  p.template.Commit,
+			Digest:  p.template.Digest,
+		}
+	}
+	for _, entry := range p.Files() {
+		lock.Files[entry.lockPath()] = HashContent(entry.content)
+	}
+	return lock
+}
+
+// lockData encodes the lockfile for the files of the plan
+func (p *Plan) lockData() ([]byte, error) {
+	lock := p.lock()
+	return lock.encode()
+}
+
+// addLockfile adds the lockfile for the files already in the plan
+func (p *Plan) addLockfile() error {
+	data, err := p.lockData()
+	if err != nil {
+		return err
+	}
+	p.addFile(LockFile, "", data)
+	return nil
+}
+
+// basePath returns where the copy of the generated file p is kept
+func basePath(p string) string {
+	return path.Join(BaseDir, p)
+}
+
+// addBase adds a copy of the template output already in the plan below BaseDir
+func (p *Plan) addBase() {
+	for _, entry := range p.Files() {
+		if entry.Path == LockFile {
+			continue
+		}
+		target := basePath(entry.lockPath())
+		p.addParents(target)
+		p.addFile(target, entry.Source, entry.content)
+	}
+}
+
+// readBase returns the copies of the files recorded in lock, leaving out
+// those missing or not matching their recorded hash
+func readBase(dir string, lock *Lock) map[string][]byte {
+	base := map[string][]byte{}
+	for p, hash := range lock.Files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(basePath(p))))
+		if err == nil && HashContent(content) == hash {
+			base[p] = content
+		}
+	}
+	return base
+}
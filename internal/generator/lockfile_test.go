@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateWritesLockfile(t *testing.T) {
+	isolateTemplateDirs(t)
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	config := ProjectConfig{
+		ProjectName: "my-api",
+		ModuleName:  "example.com/my-api",
+		AppType:     "web-api",
+		Package:     "stdlib",
+		TargetDir:   targetDir,
+		Vars:        map[string]any{"port": 9090},
+	}
+
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	lock, err := ReadLock(targetDir)
+	if err != nil {
+		t.Fatalf("ReadLock failed: %v", err)
+	}
+	if lock.GoTenVersion == "" || lock.GeneratedAt.IsZero() {
+		t.Errorf("Lockfile misses the go-ten version or time: %+v", lock)
+	}
+	if lock.Template.ID != "web-api-stdlib" || lock.Template.Source != SourceEmbedded || lock.Template.Version != "0.1.0" {
+		t.Errorf("Unexpected template in lockfile: %+v", lock.Template)
+	}
+
+	// The resolved answers include the template defaults
+	if lock.Config.ModuleName != "example.com/my-api" || lock.Config.GoVersion != "1.21" || lock.Config.TargetDir != "" {
+		t.Errorf("Unexpected config in lockfile: %+v", lock.Config)
+	}
+	if lock.Config.Vars["port"] != float64(9090) || lock.Config.Vars["cors"] != true {
+		t.Errorf("Unexpected vars in lockfile: %v", lock.Config.Vars)
+	}
+
+	// Every generated file is hashed, and nothing else
+	if _, ok := lock.Files[LockFile]; ok {
+		t.Error("Lockfile should not list itself")
+	}
+	for path, hash := range lock.Files {
+		content, err := os.ReadFile(filepath.Join(targetDir, filepath.FromSlash(path)))
+		if err != nil {
+			t.Errorf("Lockfile lists %s, which was not generated", path)
+			continue
+		}
+		if HashContent(content) != hash {
+			t.Errorf("Hash of %s does not match its content", path)
+		}
+	}
+	if _, ok := lock.Files["cmd/my-api/main.go"]; !ok {
+		t.Errorf("Lockfile misses generated files: %v", lock.Files)
+	}
+}
+
+func TestLockfileSkippedFiles(t *testing.T) {
+	isolateTemplateDirs(t)
+	targetDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(targetDir, "go.mod"), []byte("module existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := ProjectConfig{ProjectName: "my-api", ModuleName: "my-api", AppType: "web-api", Package: "stdlib", TargetDir: targetDir, OnConflict: ConflictSkip}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Files kept from before were not generated
+	lock, err := ReadLock(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lock.Files["go.mod"]; ok {
+		t.Error("Lockfile should not list skipped files")
+	}
+
+	// Generating again replaces the lockfile instead of conflicting with it
+	config.OnConflict = ConflictOverwrite
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	lock, err = ReadLock(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lock.Files["go.mod"]; !ok {
+		t.Error("Lockfile should list the overwritten go.mod")
+	}
+}
@@ -0,0 +1,435 @@
+package generator
+
+import (
+	"fmt"
+	"go/version"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the name of the manifest at the root of every template
+const ManifestFile = "template.yaml"
+
+// VarType is the type of a template variable
+type VarType string
+
+const (
+	VarString      VarType = "string"
+	VarInt         VarType = "int"
+	VarBool        VarType = "bool"
+	VarSelect      VarType = "select"      // one of Choices
+	VarMultiSelect VarType = "multiselect" // any subset of Choices
+)
+
+// Manifest describes a template and the variables it accepts
+type Manifest struct {
+	Name        string     `yaml:"name"`        // display name, e.g. "Web API"
+	Description string     `yaml:"description"` // one line summary
+	AppType     string     `yaml:"appType"`     // e.g. "web-api"
+	Package     string     `yaml:"package"`     // e.g. "stdlib"
+	Version     string     `yaml:"version"`     // template version
+	GoVersion   string     `yaml:"goVersion"`   // minimum Go version of generated projects, e.g. "1.21"
+	NextSteps   []string   `yaml:"nextSteps"`   // commands shown after generation; may use template fields
+	Variables   []Variable `yaml:"variables"`
+	Files       []FileRule `yaml:"files"` // conditional paths
+
+	// PostGenerate declares the commands run in the project after generation
+	PostGenerate []PostStep `yaml:"postGenerate"`
+	// Gitignore is written as .gitignore when a git repository is created; may use template fields
+	Gitignore string `yaml:"gitignore"`
+}
+
+// Variable is a typed value that a template can use as .Vars.<Name>
+type Variable struct {
+	Name        string   `yaml:"name"`
+	Type        VarType  `yaml:"type"`
+	Prompt      string   `yaml:"prompt"`
+	Description string   `yaml:"description"`
+	Default     any      `yaml:"default"`
+	Pattern     string   `yaml:"pattern"` // regular expression string values must match
+	Choices     []string `yaml:"choices"` // allowed values for select and multiselect
+	Required    bool     `yaml:"required"`
+}
+
+// Template is a template directory together with its manifest
+type Template struct {
+	Manifest
+	Dir    string // directory of the template, e.g. "templates/web-api-stdlib"
+	FS     fs.FS  // files of the template, rooted at Dir
+	Source string // where the template was found: SourceEmbedded, a directory on disk or a git source
+	Path   string // directory of the template on disk; empty for embedded templates
+	Commit string // commit checked out for git sources
+	Digest string // digest recorded by the template cache, checked before generation
+}
+
+// validVarName matches variable names usable as .Vars.<name> in templates
+var validVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Templates returns every available template from the template directories
+// and the embedded ones, sorted by app type and package
+func Templates() ([]Template, error) {
+	return TemplatesIn("")
+}
+
+// FindTemplate returns the template for an app type and package
+func FindTemplate(appType, packageName string) (*Template, error) {
+	return FindTemplateIn("", appType, packageName)
+}
+
+// ID returns the "{appType}-{package}" identifier of the template
+func (t Template) ID() string {
+	return fmt.Sprintf("%s-%s", t.AppType, t.Package)
+}
+
+// discoverTemplates loads every directory below root that has a manifest
+func discoverTemplates(fsys fs.FS, root string) ([]Template, error) {
+	entries, err := fs.ReadDir(fsys, root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates directory %s: %w", root, err)
+	}
+
+	var templates []Template
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := path.Join(root, entry.Name())
+		if _, err := fs.Stat(fsys, path.Join(dir, ManifestFile)); err != nil {
+			continue // not a template
+		}
+
+		tmpl, err := loadTemplate(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *tmpl)
+	}
+
+	sortTemplates(templates)
+	return templates, nil
+}
+
+// loadTemplate reads and validates the manifest of the template in dir
+func loadTemplate(fsys fs.FS, dir string) (*Template, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest of %s: %w", dir, err)
+	}
+
+	manifest, err := ParseManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path.Join(dir, ManifestFile), err)
+	}
+
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open template %s: %w", dir, err)
+	}
+
+	return &Template{Manifest: *manifest, Dir: dir, FS: sub}, nil
+}
+
+// ParseManifest decodes and validates a template manifest
+func ParseManifest(data []byte) (*Manifest, error) {
+	var manifest Manifest
+
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, err
+	}
+
+	if err := manifest.validate(); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// validate checks the manifest fields and the defaults of every variable
+func (m *Manifest) validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if m.AppType == "" || m.Package == "" {
+		return fmt.Errorf("appType and package are required")
+	}
+	if m.GoVersion != "" && !version.IsValid("go"+m.GoVersion) {
+		return fmt.Errorf("invalid goVersion %q", m.GoVersion)
+	}
+
+	seen := map[string]bool{}
+	for i := range m.Variables {
+		v := &m.Variables[i]
+		if !validVarName.MatchString(v.Name) {
+			return fmt.Errorf("invalid variable name %q", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("duplicate variable %q", v.Name)
+		}
+		seen[v.Name] = true
+
+		if v.Type == "" {
+			v.Type = VarString
+		}
+		if err := v.validateDefinition(); err != nil {
+			return fmt.Errorf("variable %s: %w", v.Name, err)
+		}
+	}
+
+	for _, step := range m.NextSteps {
+		if _, err := newTemplate("nextSteps").Parse(step); err != nil {
+			return fmt.Errorf("invalid next step %q: %w", step, err)
+		}
+	}
+
+	for i, rule := range m.Files {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("files[%d]: %w", i, err)
+		}
+	}
+
+	if _, err := newTemplate("gitignore").Parse(m.Gitignore); err != nil {
+		return fmt.Errorf("invalid gitignore: %w", err)
+	}
+
+	steps := map[string]bool{}
+	for _, step := range m.PostGenerate {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("postGenerate: %w", err)
+		}
+		if steps[step.Name] {
+			return fmt.Errorf("postGenerate: duplicate step %q", step.Name)
+		}
+		steps[step.Name] = true
+	}
+
+	return nil
+}
+
+// validateDefinition checks the type, pattern, choices and default of v
+func (v Variable) validateDefinition() error {
+	switch v.Type {
+	case VarString, VarInt, VarBool:
+	case VarSelect, VarMultiSelect:
+		if len(v.Choices) == 0 {
+			return fmt.Errorf("%s requires choices", v.Type)
+		}
+	default:
+		return fmt.Errorf("unknown type %q", v.Type)
+	}
+
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+
+	if v.Default != nil {
+		if _, err := v.Convert(v.Default); err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ResolveVars applies defaults to vars and converts and validates every
+// value against the manifest. Unknown variables are rejected.
+func (m *Manifest) ResolveVars(vars map[string]any) (map[string]any, error) {
+	resolved := make(map[string]any, len(m.Variables))
+
+	known := map[string]bool{}
+	for _, v := range m.Variables {
+		known[v.Name] = true
+
+		value, ok := vars[v.Name]
+		if !ok || value == nil {
+			if v.Default == nil {
+				if v.Required {
+					return nil, fmt.Errorf("variable %s is required", v.Name)
+				}
+				// Unset optional variables are not validated
+				resolved[v.Name] = v.zero()
+				continue
+			}
+			value = v.Default
+		}
+
+		converted, err := v.Convert(value)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", v.Name, err)
+		}
+		if v.Required && converted == "" {
+			return nil, fmt.Errorf("variable %s is required", v.Name)
+		}
+		resolved[v.Name] = converted
+	}
+
+	for name := range vars {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown variable %q for template %s-%s", name, m.AppType, m.Package)
+		}
+	}
+
+	return resolved, nil
+}
+
+// zero returns the value of an unset optional variable
+func (v Variable) zero() any {
+	switch v.Type {
+	case VarInt:
+		return 0
+	case VarBool:
+		return false
+	case VarMultiSelect:
+		return []string{}
+	default:
+		return ""
+	}
+}
+
+// Convert converts value to the Go type of the variable and validates it.
+// Strings are parsed, so values from flags and prompts can be passed as is.
+func (v Variable) Convert(value any) (any, error) {
+	switch v.Type {
+	case VarInt:
+		switch n := value.(type) {
+		case int:
+			return n, nil
+		case int64:
+			return int(n), nil
+		case float64:
+			if n != float64(int(n)) {
+				return nil, fmt.Errorf("%v is not an integer", n)
+			}
+			return int(n), nil
+		case string:
+			i, err := strconv.Atoi(strings.TrimSpace(n))
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an integer", n)
+			}
+			return i, nil
+		}
+		return nil, fmt.Errorf("%v is not an integer", value)
+
+	case VarBool:
+		switch b := value.(type) {
+		case bool:
+			return b, nil
+		case string:
+			parsed, err := strconv.ParseBool(strings.TrimSpace(b))
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a boolean", b)
+			}
+			return parsed, nil
+		}
+		return nil, fmt.Errorf("%v is not a boolean", value)
+
+	case VarMultiSelect:
+		var values []string
+		switch list := value.(type) {
+		case []string:
+			values = list
+		case []any:
+			for _, item := range list {
+				values = append(values, fmt.Sprint(item))
+			}
+		case string:
+			for _, item := range strings.Split(list, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%v is not a list", value)
+		}
+		for _, item := range values {
+			if !v.allows(item) {
+				return nil, fmt.Errorf("%q is not one of %s", item, strings.Join(v.Choices, ", "))
+			}
+		}
+		if values == nil {
+			values = []string{}
+		}
+		return values, nil
+
+	default:
+		s, ok := value.(string)
+		if !ok {
+			// Allow scalars such as numbers written without quotes in YAML
+			switch value.(type) {
+			case int, int64, float64, bool:
+				s = fmt.Sprint(value)
+			default:
+				return nil, fmt.Errorf("%v is not a string", value)
+			}
+		}
+		if v.Type == VarSelect && !v.allows(s) {
+			return nil, fmt.Errorf("%q is not one of %s", s, strings.Join(v.Choices, ", "))
+		}
+		if v.Pattern != "" && !regexp.MustCompile(v.Pattern).MatchString(s) {
+			return nil, fmt.Errorf("%q does not match %s", s, v.Pattern)
+		}
+		return s, nil
+	}
+}
+
+// allows reports whether choice is one of the variable's choices
+func (v Variable) allows(choice string) bool {
+	for _, c := range v.Choices {
+		if c == choice {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns config with the template's defaults filled in and its
+// variables converted and validated
+func (t *Template) apply(config ProjectConfig) (ProjectConfig, error) {
+	if config.GoVersion == "" {
+		config.GoVersion = t.GoVersion
+	}
+	if config.GoVersion != "" && t.GoVersion != "" {
+		if !version.IsValid("go" + config.GoVersion) {
+			return ProjectConfig{}, fmt.Errorf("invalid Go version %q", config.GoVersion)
+		}
+		if version.Compare("go"+config.GoVersion, "go"+t.GoVersion) < 0 {
+			return ProjectConfig{}, fmt.Errorf("template %s requires Go %s or later, got %s", t.ID(), t.GoVersion, config.GoVersion)
+		}
+	}
+
+	vars, err := t.ResolveVars(config.Vars)
+	if err != nil {
+		return ProjectConfig{}, err
+	}
+	config.Vars = vars
+
+	return config, nil
+}
+
+// RenderNextSteps returns the template's next steps rendered for config
+func (t *Template) RenderNextSteps(config ProjectConfig) ([]string, error) {
+	config, err := t.apply(config)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]string, 0, len(t.NextSteps))
+	for _, step := range t.NextSteps {
+		rendered, err := processTemplate(step, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render next step %q: %w", step, err)
+		}
+		steps = append(steps, rendered)
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplates(t *testing.T) {
+	templates, err := Templates()
+	if err != nil {
+		t.Fatalf("Templates failed: %v", err)
+	}
+
+	found := false
+	for _, tmpl := range templates {
+		if tmpl.ID() == "web-api-stdlib" {
+			found = true
+			if tmpl.Name == "" || tmpl.Description == "" || tmpl.GoVersion == "" {
+				t.Errorf("Incomplete manifest: %+v", tmpl.Manifest)
+			}
+		}
+	}
+	if !found {
+		t.Error("web-api-stdlib template was not discovered")
+	}
+
+	if _, err := FindTemplate("web-api", "invalid"); err == nil {
+		t.Error("FindTemplate should have failed with invalid package")
+	}
+}
+
+func TestParseManifestInvalid(t *testing.T) {
+	invalid := []string{
+		"description: no name\nappType: web-api\npackage: stdlib\n",
+		"name: X\nappType: web-api\n",
+		"name: X\nappType: web-api\npackage: stdlib\ngoVersion: latest\n",
+		"name: X\nappType: web-api\npackage: stdlib\nvariables:\n  - name: bad-name\n",
+		"name: X\nappType: web-api\npackage: stdlib\nvariables:\n  - name: db\n    type: select\n",
+		"name: X\nappType: web-api\npackage: stdlib\nvariables:\n  - name: port\n    type: int\n    default: abc\n",
+		"name: X\nappType: web-api\npackage: stdlib\nunknown: field\n",
+	}
+
+	for _, content := range invalid {
+		if _, err := ParseManifest([]byte(content)); err == nil {
+			t.Errorf("ParseManifest should have failed for:\n%s", content)
+		}
+	}
+}
+
+func TestResolveVars(t *testing.T) {
+	manifest, err := ParseManifest([]byte(`name: X
+appType: web-api
+package: stdlib
+variables:
+  - name: port
+    type: int
+    default: 8080
+  - name: author
+    pattern: "^[a-z]+$"
+  - name: docker
+    type: bool
+  - name: db
+    type: select
+    choices: [postgres, sqlite]
+    default: sqlite
+  - name: features
+    type: multiselect
+    choices: [cors, metrics]
+`))
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+
+	vars, err := manifest.ResolveVars(map[string]any{"port": "9090", "docker": "true", "features": "cors,metrics"})
+	if err != nil {
+		t.Fatalf("ResolveVars failed: %v", err)
+	}
+	if vars["port"] != 9090 || vars["docker"] != true || vars["db"] != "sqlite" || vars["author"] != "" {
+		t.Errorf("Unexpected vars: %v", vars)
+	}
+	if features := vars["features"].([]string); len(features) != 2 {
+		t.Errorf("Expected two features, got %v", features)
+	}
+
+	invalid := []map[string]any{
+		{"port": "abc"},
+		{"author": "Not Lower"},
+		{"db": "mysql"},
+		{"features": []any{"tracing"}},
+		{"unknown": "x"},
+	}
+	for _, vars := range invalid {
+		if _, err := manifest.ResolveVars(vars); err == nil {
+			t.Errorf("ResolveVars should have failed for %v", vars)
+		}
+	}
+}
+
+func TestDryRunUsesManifest(t *testing.T) {
+	config := ProjectConfig{
+		ProjectName: "test-project",
+		ModuleName:  "test-project",
+		AppType:     "web-api",
+		Package:     "stdlib",
+		TargetDir:   t.TempDir(),
+		Vars:        map[string]any{"port": 9090},
+	}
+
+	plan, err := DryRun(config)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+
+	for _, entry := range plan.Entries {
+		if entry.Path == ManifestFile {
+			t.Error("The manifest should not be generated")
+		}
+		if entry.Path == "utils/constants.go" && !strings.Contains(string(entry.content), `DefaultPort        = "9090"`) {
+			t.Errorf("Port variable was not applied:\n%s", entry.content)
+		}
+	}
+
+	config.GoVersion = "1.20"
+	if _, err := DryRun(config); err == nil {
+		t.Error("DryRun should have failed with a Go version below the template minimum")
+	}
+}
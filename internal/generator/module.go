@@ -0,0 +1,186 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// ModulePrefixEnv names the environment variable holding an organisation wide
+// module prefix, e.g. "github.com/ourorg". New projects default to
+// "<prefix>/<project name>".
+const ModulePrefixEnv = "GO_TEN_MODULE_PREFIX"
+
+// ValidateModulePath checks a Go module path using the same rules as "go mod init"
+func ValidateModulePath(modulePath string) error {
+	if strings.TrimSpace(modulePath) == "" {
+		return fmt.Errorf("module path cannot be empty")
+	}
+	if err := module.CheckImportPath(modulePath); err != nil {
+		return fmt.Errorf("invalid module path: %w", err)
+	}
+	return nil
+}
+
+// DefaultModulePath suggests a module path for a project named name that is
+// generated into targetDir. In order of preference it uses:
+//
+//   - the organisation prefix from $GO_TEN_MODULE_PREFIX
+//   - the origin remote of the git repository containing targetDir
+//   - the location of targetDir below GOPATH/src
+//   - the project name itself
+func DefaultModulePath(name, targetDir string) string {
+	if prefix := strings.Trim(os.Getenv(ModulePrefixEnv), "/"); prefix != "" {
+		if modulePath := path.Join(prefix, name); ValidateModulePath(modulePath) == nil {
+			return modulePath
+		}
+	}
+
+	dir, err := filepath.Abs(targetDir)
+	if err != nil {
+		return name
+	}
+
+	if modulePath := modulePathFromGit(dir); modulePath != "" {
+		return modulePath
+	}
+	if modulePath := modulePathFromParent(dir, build.Default.GOPATH); modulePath != "" {
+		return modulePath
+	}
+
+	return name
+}
+
+// modulePathFromGit derives a module path from the origin remote of the git
+// repository containing dir, adding the path of dir inside the repository
+func modulePathFromGit(dir string) string {
+	root, gitDir := findGitDir(dir)
+	if gitDir == "" {
+		return ""
+	}
+
+	base := modulePathFromRemote(gitRemoteURL(gitDir, "origin"))
+	if base == "" {
+		return ""
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || !filepath.IsLocal(rel) {
+		return ""
+	}
+	modulePath := path.Join(base, filepath.ToSlash(rel))
+	if ValidateModulePath(modulePath) != nil {
+		return ""
+	}
+	return modulePath
+}
+
+// findGitDir walks up from dir to the closest git work tree and returns its
+// root and git directory
+func findGitDir(dir string) (root, gitDir string) {
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dir, dotGit
+			}
+			// Submodules and worktrees point to their git directory from a file
+			if data, err := os.ReadFile(dotGit); err == nil {
+				if target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:"); ok {
+					target = strings.TrimSpace(target)
+					if !filepath.IsAbs(target) {
+						target = filepath.Join(dir, target)
+					}
+					return dir, target
+				}
+			}
+			return "", ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// gitRemoteURL reads the URL of remote from the config of a git directory
+func gitRemoteURL(gitDir, remote string) string {
+	f, err := os.Open(filepath.Join(gitDir, "config"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	section := fmt.Sprintf(`[remote "%s"]`, remote)
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = line == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == "url" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// modulePathFromRemote converts a git remote URL such as
+// "https://github.com/ourorg/my-api.git" or "git@github.com:ourorg/my-api.git"
+// into a module path. Local remotes yield "".
+func modulePathFromRemote(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSpace(remote), "/")
+	remote = strings.TrimSuffix(remote, ".git")
+
+	var host, repoPath string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" {
+		switch u.Scheme {
+		case "https", "http", "ssh", "git", "git+ssh":
+			host, repoPath = u.Hostname(), u.Path
+		default:
+			return ""
+		}
+	} else if before, after, ok := strings.Cut(remote, ":"); ok && !strings.Contains(before, "/") {
+		// scp-like syntax: [user@]host:path
+		if i := strings.LastIndex(before, "@"); i >= 0 {
+			before = before[i+1:]
+		}
+		host, repoPath = before, after
+	} else {
+		return ""
+	}
+
+	modulePath := strings.ToLower(host) + "/" + strings.Trim(repoPath, "/")
+	if module.CheckPath(modulePath) != nil {
+		return ""
+	}
+	return modulePath
+}
+
+// modulePathFromParent derives a module path from where dir lives below
+// GOPATH/src, like "go mod init" does
+func modulePathFromParent(dir, gopath string) string {
+	for _, root := range filepath.SplitList(gopath) {
+		rel, err := filepath.Rel(filepath.Join(root, "src"), dir)
+		if err == nil && filepath.IsLocal(rel) {
+			if modulePath := filepath.ToSlash(rel); module.CheckPath(modulePath) == nil {
+				return modulePath
+			}
+		}
+	}
+	return ""
+}
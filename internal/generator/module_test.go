@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateModulePath(t *testing.T) {
+	valid := []string{"my-api", "github.com/ourorg/my-api", "example.com/a/b/v2"}
+	for _, path := range valid {
+		if err := ValidateModulePath(path); err != nil {
+			t.Errorf("ValidateModulePath(%q) failed: %v", path, err)
+		}
+	}
+
+	invalid := []string{"", "/my-api", "my api", "github.com/ourorg/", "a/../b"}
+	for _, path := range invalid {
+		if err := ValidateModulePath(path); err == nil {
+			t.Errorf("ValidateModulePath(%q) should have failed", path)
+		}
+	}
+}
+
+func TestModulePathFromRemote(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/ourorg/my-api.git":  "github.com/ourorg/my-api",
+		"https://GitHub.com/ourorg/my-api/":     "github.com/ourorg/my-api",
+		"git@github.com:ourorg/my-api.git":      "github.com/ourorg/my-api",
+		"ssh://git@gitlab.com:2222/group/sub/x": "gitlab.com/group/sub/x",
+		"/srv/git/my-api.git":                   "",
+		"file:///srv/git/my-api.git":            "",
+		"":                                      "",
+	}
+	for remote, want := range tests {
+		if got := modulePathFromRemote(remote); got != want {
+			t.Errorf("modulePathFromRemote(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
+
+func TestDefaultModulePath(t *testing.T) {
+	t.Setenv(ModulePrefixEnv, "")
+	root := t.TempDir()
+
+	// Outside a repository the project name is used
+	if got := DefaultModulePath("my-api", filepath.Join(root, "my-api")); got != "my-api" {
+		t.Errorf("DefaultModulePath() = %q, want %q", got, "my-api")
+	}
+
+	// Inside a repository the origin remote is used
+	gitConfig := "[core]\n\tbare = false\n[remote \"origin\"]\n\turl = git@github.com:ourorg/services.git\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n"
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".git", "config"), []byte(gitConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := DefaultModulePath("my-api", root); got != "github.com/ourorg/services" {
+		t.Errorf("DefaultModulePath() at the repository root = %q", got)
+	}
+	if got := DefaultModulePath("my-api", filepath.Join(root, "my-api")); got != "github.com/ourorg/services/my-api" {
+		t.Errorf("DefaultModulePath() below the repository root = %q", got)
+	}
+
+	// The organisation prefix wins
+	t.Setenv(ModulePrefixEnv, "example.com/ourorg/")
+	if got := DefaultModulePath("my-api", root); got != "example.com/ourorg/my-api" {
+		t.Errorf("DefaultModulePath() with prefix = %q", got)
+	}
+}
+
+func TestModulePathFromParent(t *testing.T) {
+	gopath := t.TempDir()
+	dir := filepath.Join(gopath, "src", "github.com", "ourorg", "my-api")
+
+	if got := modulePathFromParent(dir, gopath); got != "github.com/ourorg/my-api" {
+		t.Errorf("modulePathFromParent() = %q, want %q", got, "github.com/ourorg/my-api")
+	}
+	if got := modulePathFromParent(t.TempDir(), gopath); got != "" {
+		t.Errorf("modulePathFromParent() outside GOPATH = %q, want empty", got)
+	}
+}
@@ -0,0 +1,227 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Modes used for generated directories and files
+const (
+	dirMode  fs.FileMode = fs.ModeDir | 0755
+	fileMode fs.FileMode = 0644
+)
+
+// PlanEntry describes a directory or file that generation would create
+type PlanEntry struct {
+	Path   string      `json:"path"`   // final path relative to the target directory, slash separated
+	Source string      `json:"source"` // path inside the template
+	IsDir  bool        `json:"isDir"`
+	Size   int64       `json:"size"`
+	Mode   fs.FileMode `json:"mode"`
+
+	// FormatError says why a Go file could not be formatted and was kept as rendered
+	FormatError string `json:"formatError,omitempty"`
+
+	content []byte
+	planned string // path the template planned when a conflict moved the file to Path
+}
+
+// lockPath returns the path the lockfile records the entry under
+func (e PlanEntry) lockPath() string {
+	if e.planned != "" {
+		return e.planned
+	}
+	return e.Path
+}
+
+// Plan lists everything that generating a project would write
+type Plan struct {
+	TargetDir string      `json:"targetDir"`
+	Entries   []PlanEntry `json:"entries"`
+
+	// removals are files deleted from the target along with writing the entries
+	removals []string
+
+	// template and the config with its defaults applied, recorded in the lockfile
+	template *Template
+	config   ProjectConfig
+}
+
+// addDir records a directory in the plan
+func (p *Plan) addDir(targetPath, sourcePath string) {
+	p.Entries = append(p.Entries, PlanEntry{
+		Path:   filepath.ToSlash(targetPath),
+		Source: filepath.ToSlash(sourcePath),
+		IsDir:  true,
+		Mode:   dirMode,
+	})
+}
+
+// addFile records a rendered file in the plan
+func (p *Plan) addFile(targetPath, sourcePath string, content []byte) {
+	p.addRenderedFile(targetPath, sourcePath, content, nil)
+}
+
+// addRenderedFile records a rendered file in the plan along with the error
+// that kept it from being formatted, if any
+func (p *Plan) addRenderedFile(targetPath, sourcePath string, content []byte, formatErr error) {
+	entry := PlanEntry{
+		Path:    filepath.ToSlash(targetPath),
+		Source:  filepath.ToSlash(sourcePath),
+		Size:    int64(len(content)),
+		Mode:    fileMode,
+		content: content,
+	}
+	if formatErr != nil {
+		entry.FormatError = formatErr.Error()
+	}
+	p.Entries = append(p.Entries, entry)
+}
+
+// has reports whether the plan contains an entry for path
+func (p *Plan) has(path string) bool {
+	for _, entry := range p.Entries {
+		if entry.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Config returns the config the plan was rendered for, with the template
+// defaults applied
+func (p *Plan) Config() ProjectConfig {
+	return p.config
+}
+
+// FormatErrors lists the Go files that could not be formatted and were
+// written as rendered, with the reason
+func (p *Plan) FormatErrors() []string {
+	var errs []string
+	for _, entry := range p.Files() {
+		if entry.FormatError != "" {
+			errs = append(errs, fmt.Sprintf("%s was written unformatted: %s", entry.Path, entry.FormatError))
+		}
+	}
+	return errs
+}
+
+// Files returns the file entries of the plan
+func (p *Plan) Files() []PlanEntry {
+	var files []PlanEntry
+	for _, entry := range p.Entries {
+		if !entry.IsDir {
+			files = append(files, entry)
+		}
+	}
+	return files
+}
+
+// Contents returns the rendered files of the plan by path, without the lockfile
+func (p *Plan) Contents() map[string][]byte {
+	files := map[string][]byte{}
+	for _, entry := range p.Files() {
+		if entry.Path != LockFile {
+			files[entry.Path] = entry.content
+		}
+	}
+	return files
+}
+
+// write creates the planned directories and files below targetDir, stopping
+// when ctx is cancelled
+func (p *Plan) write(ctx context.Context, targetDir string) error {
+	for _, entry := range p.Entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		targetPath := filepath.Join(targetDir, filepath.FromSlash(entry.Path))
+
+		if entry.IsDir {
+			if err := createDirectory(targetPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.WriteFile(targetPath, entry.content, entry.Mode.Perm()); err != nil {
+			return fmt.Errorf("failed to write target file %s: %w", targetPath, err)
+		}
+	}
+
+	return nil
+}
+
+// String lists every entry with its mode, size and final path
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, entry := range p.Entries {
+		name := entry.Path
+		if entry.IsDir {
+			name += "/"
+		}
+		fmt.Fprintf(&b, "%s %8d  %s\n", entry.Mode, entry.Size, name)
+	}
+	return b.String()
+}
+
+// treeNode is a directory or file in the rendered plan tree
+type treeNode struct {
+	name     string
+	entry    PlanEntry
+	children map[string]*treeNode
+}
+
+// Tree renders the plan as a directory tree rooted at the target directory
+func (p *Plan) Tree() string {
+	root := &treeNode{name: p.TargetDir, children: map[string]*treeNode{}}
+
+	for _, entry := range p.Entries {
+		node := root
+		for _, part := range strings.Split(entry.Path, "/") {
+			child, ok := node.children[part]
+			if !ok {
+				child = &treeNode{name: part, children: map[string]*treeNode{}}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.entry = entry
+	}
+
+	var b strings.Builder
+	b.WriteString(root.name + "\n")
+	root.render(&b, "")
+	return b.String()
+}
+
+// render writes the children of n with box drawing prefixes
+func (n *treeNode) render(b *strings.Builder, prefix string) {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		child := n.children[name]
+		last := i == len(names)-1
+
+		branch, indent := "├── ", "│   "
+		if last {
+			branch, indent = "└── ", "    "
+		}
+
+		if child.entry.IsDir {
+			fmt.Fprintf(b, "%s%s%s/\n", prefix, branch, child.name)
+		} else {
+			fmt.Fprintf(b, "%s%s%s (%s, %d bytes)\n", prefix, branch, child.name, child.entry.Mode, child.entry.Size)
+		}
+		child.render(b, prefix+indent)
+	}
+}
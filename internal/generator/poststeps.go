@@ -0,0 +1,249 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/manuelbamise/go-ten/internal/commands"
+)
+
+// DefaultPostStepTimeout limits post-generation steps that set no timeout
+const DefaultPostStepTimeout = 5 * time.Minute
+
+// PostStepsNone selects no post-generation steps, e.g. --post-generate none
+const PostStepsNone = "none"
+
+// PostStep is a command run in the project after it was generated
+type PostStep struct {
+	Name        string            `yaml:"name"`        // built-in step, or a name for a custom one
+	Description string            `yaml:"description"` // shown when choosing steps
+	Run         string            `yaml:"run"`         // command line, may use template fields; built-in steps have one
+	Default     bool              `yaml:"default"`     // preselected when choosing steps
+	Timeout     time.Duration     `yaml:"timeout"`     // defaults to DefaultPostStepTimeout
+	Env         map[string]string `yaml:"env"`         // added to the environment
+}
+
+// builtinPostSteps can be chosen for every template, in this order
+var builtinPostSteps = []PostStep{
+	{Name: "tidy", Description: "Add missing and remove unused modules", Run: "go mod tidy"},
+	{Name: "fmt", Description: "Format the Go files", Run: "gofmt -w ."},
+	{Name: "build", Description: "Check that the project builds", Run: "go build ./..."},
+}
+
+// builtinPostStep returns the built-in step with name
+func builtinPostStep(name string) (PostStep, bool) {
+	for _, step := range builtinPostSteps {
+		if step.Name == name {
+			return step, true
+		}
+	}
+	return PostStep{}, false
+}
+
+// validate checks a step declared in a manifest
+func (s PostStep) validate() error {
+	if !validVarName.MatchString(s.Name) || s.Name == PostStepsNone {
+		return fmt.Errorf("invalid name %q", s.Name)
+	}
+	if _, ok := builtinPostStep(s.Name); !ok && strings.TrimSpace(s.Run) == "" {
+		return fmt.Errorf("%s: run is required", s.Name)
+	}
+	if s.Run != "" {
+		if _, err := newTemplate("postGenerate").Parse(s.Run); err != nil {
+			return fmt.Errorf("%s: invalid run: %w", s.Name, err)
+		}
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("%s: negative timeout", s.Name)
+	}
+	return nil
+}
+
+// PostSteps returns the steps offered after generating the template: those
+// its manifest declares, in order, followed by the remaining built-in ones.
+// Declared built-in steps keep their command unless the manifest sets one.
+func (t *Template) PostSteps() []PostStep {
+	steps := make([]PostStep, 0, len(t.PostGenerate)+len(builtinPostSteps))
+	declared := map[string]bool{}
+	for _, step := range t.PostGenerate {
+		if builtin, ok := builtinPostStep(step.Name); ok {
+			if step.Run == "" {
+				step.Run = builtin.Run
+			}
+			if step.Description == "" {
+				step.Description = builtin.Description
+			}
+		}
+		declared[step.Name] = true
+		steps = append(steps, step)
+	}
+	for _, step := range builtinPostSteps {
+		if !declared[step.Name] {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// SelectPostSteps returns the post-generation steps to run for config, with
+// their commands rendered. Steps only run when config.PostGenerate names
+// them, whatever the template marks as default; they run in the order of
+// PostSteps.
+func (t *Template) SelectPostSteps(config ProjectConfig) ([]PostStep, error) {
+	available := t.PostSteps()
+
+	var names []string
+	for _, step := range available {
+		names = append(names, step.Name)
+	}
+	selected := map[string]bool{}
+	for _, name := range config.PostGenerate {
+		if name == PostStepsNone {
+			continue
+		}
+		if !contains(names, name) {
+			return nil, fmt.Errorf("unknown post-generation step %q (available: %s)", name, strings.Join(names, ", "))
+		}
+		selected[name] = true
+	}
+
+	config, err := t.apply(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []PostStep
+	for _, step := range available {
+		if !selected[step.Name] {
+			continue
+		}
+		rendered, err := processTemplate(step.Run, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render post-generation step %s: %w", step.Name, err)
+		}
+		step.Run = rendered
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// PostStepsFor returns the post-generation steps to run for config
+func PostStepsFor(config ProjectConfig) ([]PostStep, error) {
+	tmpl, err := FindTemplateIn(config.Template, config.AppType, config.Package)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.SelectPostSteps(config)
+}
+
+// Command returns the command that runs the step in dir
+func (s PostStep) Command(dir string) (commands.Command, error) {
+	words, err := commands.Split(s.Run)
+	if err != nil {
+		return commands.Command{}, fmt.Errorf("invalid post-generation step %s: %w", s.Name, err)
+	}
+	if len(words) == 0 {
+		return commands.Command{}, fmt.Errorf("post-generation step %s has no command", s.Name)
+	}
+
+	cmd := commands.Command{Name: words[0], Args: words[1:], Dir: dir, Timeout: s.Timeout}
+	if cmd.Timeout == 0 {
+		cmd.Timeout = DefaultPostStepTimeout
+	}
+	for name, value := range s.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	sort.Strings(cmd.Env)
+	return cmd, nil
+}
+
+// RunPostStep runs the command of a post-generation step in the project in
+// cmd.Dir. Generated files the command rewrites are recorded in the lockfile,
+// so that update and diff do not take them for local changes.
+func RunPostStep(ctx context.Context, cmd commands.Command) error {
+	lock, err := ReadLock(cmd.Dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// Only files still as generated can have been rewritten by the step;
+	// others, like files kept on a conflict, belong to the user
+	var generated []string
+	if lock != nil {
+		for p := range lock.Files {
+			content, err := os.ReadFile(filepath.Join(cmd.Dir, filepath.FromSlash(p)))
+			if err == nil && lock.Generated(p, content) {
+				generated = append(generated, p)
+			}
+		}
+	}
+
+	// Steps like "gofmt -w ." also walk the copy of the template output that
+	// update merges against, so it is put back as generated afterwards
+	var base map[string][]byte
+	if lock != nil {
+		base = readBase(cmd.Dir, lock)
+	}
+
+	// A failed step may have rewritten files too, so record them either way
+	_, runErr := commands.Run(ctx, cmd)
+	if lock == nil {
+		return runErr
+	}
+	if err := restoreBase(cmd.Dir, base); err != nil && runErr == nil {
+		runErr = err
+	}
+	for _, p := range generated {
+		content, err := os.ReadFile(filepath.Join(cmd.Dir, filepath.FromSlash(p)))
+		if err != nil {
+			continue
+		}
+		if hash := HashContent(content); hash == lock.Files[p] {
+			delete(lock.PostStepFiles, p)
+		} else {
+			if lock.PostStepFiles == nil {
+				lock.PostStepFiles = map[string]string{}
+			}
+			lock.PostStepFiles[p] = hash
+		}
+	}
+	if err := lock.write(cmd.Dir); err != nil && runErr == nil {
+		return err
+	}
+	return runErr
+}
+
+// restoreBase rewrites the copies in base that no longer match on disk
+func restoreBase(dir string, base map[string][]byte) error {
+	for p, content := range base {
+		target := filepath.Join(dir, filepath.FromSlash(basePath(p)))
+		if current, err := os.ReadFile(target); err == nil && bytes.Equal(current, content) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", basePath(p), err)
+		}
+		if err := os.WriteFile(target, content, fileMode); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", basePath(p), err)
+		}
+	}
+	return nil
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
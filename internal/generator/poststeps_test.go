@@ -0,0 +1,207 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manuelbamise/go-ten/internal/commands"
+)
+
+func TestPostSteps(t *testing.T) {
+	manifest, err := ParseManifest([]byte(`name: Worker
+appType: worker
+package: stdlib
+postGenerate:
+  - name: generate
+    description: Generate code
+    run: go generate ./cmd/{{.ProjectName}}
+    default: true
+    timeout: 2m
+    env:
+      GOFLAGS: -mod=mod
+  - name: tidy
+    default: true
+`))
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	tmpl := &Template{Manifest: *manifest}
+
+	// Declared steps come first, then the other built-in ones
+	var names []string
+	for _, step := range tmpl.PostSteps() {
+		names = append(names, step.Name)
+	}
+	if strings.Join(names, ",") != "generate,tidy,fmt,build" {
+		t.Errorf("Unexpected post steps: %v", names)
+	}
+
+	config := ProjectConfig{ProjectName: "my-worker", AppType: "worker", Package: "stdlib"}
+	tests := []struct {
+		selected []string
+		want     string
+	}{
+		{nil, ""},
+		{[]string{"tidy", "generate"}, "go generate ./cmd/my-worker|go mod tidy"},
+		{[]string{}, ""},
+		{[]string{PostStepsNone}, ""},
+		{[]string{"build", "fmt"}, "gofmt -w .|go build ./..."},
+	}
+	for _, tt := range tests {
+		config.PostGenerate = tt.selected
+		steps, err := tmpl.SelectPostSteps(config)
+		if err != nil {
+			t.Errorf("SelectPostSteps(%v) failed: %v", tt.selected, err)
+			continue
+		}
+		var runs []string
+		for _, step := range steps {
+			runs = append(runs, step.Run)
+		}
+		if got := strings.Join(runs, "|"); got != tt.want {
+			t.Errorf("SelectPostSteps(%v) = %q, want %q", tt.selected, got, tt.want)
+		}
+	}
+
+	config.PostGenerate = []string{"deploy"}
+	if _, err := tmpl.SelectPostSteps(config); err == nil || !strings.Contains(err.Error(), "available: generate, tidy") {
+		t.Errorf("Expected an unknown step error, got %v", err)
+	}
+
+	// Commands run in the project with the step's timeout and environment
+	config.PostGenerate = []string{"generate", "tidy"}
+	steps, _ := tmpl.SelectPostSteps(config)
+	cmd, err := steps[0].Command("/tmp/my-worker")
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if cmd.Name != "go" || strings.Join(cmd.Args, " ") != "generate ./cmd/my-worker" || cmd.Dir != "/tmp/my-worker" ||
+		cmd.Timeout != 2*time.Minute || len(cmd.Env) != 1 || cmd.Env[0] != "GOFLAGS=-mod=mod" {
+		t.Errorf("Unexpected command: %+v", cmd)
+	}
+	if cmd, _ := steps[1].Command("."); cmd.Timeout != DefaultPostStepTimeout {
+		t.Errorf("Expected the default timeout, got %s", cmd.Timeout)
+	}
+}
+
+func TestPostStepsInvalid(t *testing.T) {
+	invalid := []string{
+		"postGenerate:\n  - name: custom\n",
+		"postGenerate:\n  - name: bad-name\n    run: echo\n",
+		"postGenerate:\n  - name: none\n    run: echo\n",
+		"postGenerate:\n  - name: tidy\n  - name: tidy\n",
+		"postGenerate:\n  - name: custom\n    run: echo {{.Missing\n",
+		"postGenerate:\n  - name: tidy\n    timeout: -1s\n",
+	}
+	for _, extra := range invalid {
+		data := "name: X\nappType: x\npackage: y\n" + extra
+		if _, err := ParseManifest([]byte(data)); err == nil {
+			t.Errorf("ParseManifest should reject:\n%s", extra)
+		}
+	}
+}
+
+func TestRunPostStepRecordsRewrittenFiles(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh")
+	}
+	isolateTemplateDirs(t)
+	v1 := t.TempDir()
+	writeTemplate(t, v1, "tool", "plain", map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	v2 := t.TempDir()
+	writeTemplate(t, v2, "tool", "plain", map[string]string{"a.txt": "a\n", "b.txt": "b v2\n"})
+
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	config := ProjectConfig{ProjectName: "svc", AppType: "tool", Package: "plain", TargetDir: targetDir, Template: v1}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// A step rewriting a generated file, like go mod tidy does
+	cmd := commands.Command{Name: "/bin/sh", Args: []string{"-c", "echo tidied >> a.txt"}, Dir: targetDir, Timeout: time.Minute}
+	if err := RunPostStep(context.Background(), cmd); err != nil {
+		t.Fatalf("RunPostStep failed: %v", err)
+	}
+	lock, err := ReadLock(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.Files["a.txt"] != HashContent([]byte("a\n")) || lock.PostStepFiles["a.txt"] != HashContent([]byte("a\ntidied\n")) {
+		t.Errorf("Unexpected lockfile: %+v", lock)
+	}
+
+	// Neither diff nor update take the rewritten file for a local change
+	result, err := Drift(config)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if len(result.Files) != 0 {
+		t.Errorf("Expected no drift, got %+v", result.Files)
+	}
+	updated, err := Update(UpdateOptions{Dir: targetDir, Template: v2, From: v1})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	for _, f := range updated.Files {
+		if f.Path == "a.txt" && f.Status != UpdateUnchanged || f.Path == "b.txt" && f.Status != UpdateUpdated {
+			t.Errorf("Unexpected update of %s: %s", f.Path, f.Status)
+		}
+	}
+	if a, _ := os.ReadFile(filepath.Join(targetDir, "a.txt")); string(a) != "a\ntidied\n" {
+		t.Errorf("Update replaced a.txt: %q", a)
+	}
+	if lock, err := ReadLock(targetDir); err != nil || lock.PostStepFiles["a.txt"] == "" {
+		t.Errorf("Update dropped the post-step hash of a.txt: %+v, %v", lock, err)
+	}
+}
+
+func TestRunPostStepKeepsBase(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt is not installed")
+	}
+	isolateTemplateDirs(t)
+	templateDir := t.TempDir()
+	unformatted := "package main\nfunc main(){}\n"
+	writeTemplate(t, templateDir, "tool", "plain", map[string]string{"main.go": unformatted})
+	manifest := "name: tool\nappType: tool\npackage: plain\nfiles:\n  - paths: [main.go]\n    format: false\n"
+	if err := os.WriteFile(filepath.Join(templateDir, ManifestFile), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	config := ProjectConfig{ProjectName: "svc", AppType: "tool", Package: "plain", TargetDir: targetDir, Template: templateDir}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// The built-in fmt step formats the project, but not the copy update merges against
+	step, _ := builtinPostStep("fmt")
+	cmd, err := step.Command(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RunPostStep(context.Background(), cmd); err != nil {
+		t.Fatalf("RunPostStep failed: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(targetDir, "main.go")); string(content) == unformatted {
+		t.Error("main.go should be formatted")
+	}
+	if content, err := os.ReadFile(filepath.Join(targetDir, filepath.FromSlash(basePath("main.go")))); err != nil || string(content) != unformatted {
+		t.Errorf("The base copy of main.go changed: %q (%v)", content, err)
+	}
+
+	updated, err := Update(UpdateOptions{Dir: targetDir})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	for _, f := range updated.Files {
+		if f.Status != UpdateUnchanged {
+			t.Errorf("Unexpected update of %s: %s", f.Path, f.Status)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/manuelbamise/go-ten/internal/cache"
+)
+
+// TemplatePathEnv names the environment variable with extra template
+// directories, separated like PATH
+const TemplatePathEnv = "GO_TEN_TEMPLATE_PATH"
+
+// SourceEmbedded is the Source of the templates built into go-ten
+const SourceEmbedded = "embedded"
+
+// Warnings receives problems go-ten works around, like an unreadable
+// template cache. Each message is written once.
+var Warnings io.Writer = os.Stderr
+
+var (
+	warnedMu sync.Mutex
+	warned   = map[string]bool{}
+)
+
+// warnf writes a warning to Warnings unless it was written before
+func warnf(format string, args ...any) {
+	msg := fmt.Sprintf("Warning: "+format+"\n", args...)
+
+	warnedMu.Lock()
+	defer warnedMu.Unlock()
+	if !warned[msg] {
+		warned[msg] = true
+		io.WriteString(Warnings, msg)
+	}
+}
+
+// TemplateDirs returns the directories searched for templates, highest
+// precedence first: dir (from --template) if set, the entries of
+// $GO_TEN_TEMPLATE_PATH and the user template directory
+// (~/.config/go-ten/templates). The template cache and the embedded templates
+// come last.
+func TemplateDirs(dir string) []string {
+	var dirs []string
+	if dir != "" {
+		dirs = append(dirs, dir)
+	}
+	for _, d := range filepath.SplitList(os.Getenv(TemplatePathEnv)) {
+		if d != "" {
+			dirs = append(dirs, d)
+		}
+	}
+	if d := userTemplateDir(); d != "" {
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+// userTemplateDir returns the go-ten templates directory below
+// $XDG_CONFIG_HOME, or ~/.config when it is not set
+func userTemplateDir() string {
+	if config := os.Getenv("XDG_CONFIG_HOME"); config != "" {
+		return filepath.Join(config, "go-ten", "templates")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "go-ten", "templates")
+}
+
+// TemplatesIn returns the templates of every directory in TemplateDirs(dir),
+// the cached and the embedded ones. A template shadows those with the same app type and
+// package from directories of lower precedence. dir must exist; the other
+// directories are skipped when missing.
+func TemplatesIn(dir string) ([]Template, error) {
+	var templates []Template
+	seen := map[string]bool{}
+	add := func(found []Template) {
+		for _, tmpl := range found {
+			if !seen[tmpl.ID()] {
+				seen[tmpl.ID()] = true
+				templates = append(templates, tmpl)
+			}
+		}
+	}
+
+	for _, d := range TemplateDirs(dir) {
+		found, err := LoadTemplateDir(d)
+		if err != nil {
+			if d != dir && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		add(found)
+	}
+
+	// A broken cache must not hide the other templates
+	cached, err := cachedTemplates()
+	if err != nil {
+		warnf("skipping template cache: %v", err)
+	}
+	add(cached)
+
+	embedded, err := discoverTemplates(templateFS, "templates")
+	if err != nil {
+		return nil, err
+	}
+	for i := range embedded {
+		embedded[i].Source = SourceEmbedded
+	}
+	add(embedded)
+
+	sortTemplates(templates)
+	return templates, nil
+}
+
+// LoadTemplateDir loads the templates of a directory on disk, or of a git
+// repository given as "git+<url>@<ref>". The directory is either a template
+// itself, with a manifest at its root, or holds one template per subdirectory.
+func LoadTemplateDir(dir string) ([]Template, error) {
+	if cache.IsGitSource(dir) {
+		return loadGitTemplates(dir)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open template directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("template path %s is not a directory", dir)
+	}
+
+	fsys := os.DirFS(dir)
+
+	var templates []Template
+	if _, err := fs.Stat(fsys, ManifestFile); err == nil {
+		tmpl, err := loadTemplate(fsys, ".")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+		templates = []Template{*tmpl}
+	} else {
+		templates, err = discoverTemplates(fsys, ".")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dir, err)
+		}
+	}
+
+	for i := range templates {
+		templates[i].Path = filepath.Join(dir, filepath.FromSlash(templates[i].Dir))
+		templates[i].Source = templates[i].Path
+	}
+	return templates, nil
+}
+
+// loadGitTemplates checks out a git repository into the cache and loads its
+// templates like a local directory
+func loadGitTemplates(spec string) ([]Template, error) {
+	source, err := cache.ParseGitSource(spec)
+	if err != nil {
+		return nil, err
+	}
+	checkout, err := cache.FetchGit(source)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := LoadTemplateDir(checkout.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		templates[i].Source = spec
+		templates[i].Commit = checkout.Commit
+	}
+	return templates, nil
+}
+
+// FindTemplateIn returns the template for an app type and package, searching
+// TemplateDirs(dir) before the embedded templates
+func FindTemplateIn(dir, appType, packageName string) (*Template, error) {
+	templates, err := TemplatesIn(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var available []string
+	for i := range templates {
+		if templates[i].AppType == appType && templates[i].Package == packageName {
+			return &templates[i], nil
+		}
+		available = append(available, templates[i].ID())
+	}
+
+	return nil, fmt.Errorf("template not found: %s-%s (available templates: %s)", appType, packageName, strings.Join(available, ", "))
+}
+
+// sortTemplates sorts templates by app type and package
+func sortTemplates(templates []Template) {
+	sort.SliceStable(templates, func(i, j int) bool {
+		if templates[i].AppType != templates[j].AppType {
+			return templates[i].AppType < templates[j].AppType
+		}
+		return templates[i].Package < templates[j].Package
+	})
+}
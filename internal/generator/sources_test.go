@@ -0,0 +1,193 @@
+package generator
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manuelbamise/go-ten/internal/cache"
+)
+
+// writeTemplate creates a minimal template in dir with the given files
+func writeTemplate(t *testing.T, dir, appType, packageName string, files map[string]string) {
+	t.Helper()
+	manifest := "name: " + appType + "\nappType: " + appType + "\npackage: " + packageName + "\n"
+	files[ManifestFile] = manifest
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// isolateTemplateDirs points the template search path and cache at empty directories
+func isolateTemplateDirs(t *testing.T) (envDir, userDir string) {
+	t.Helper()
+	envDir = t.TempDir()
+	config := t.TempDir()
+	t.Setenv(TemplatePathEnv, envDir)
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	return envDir, filepath.Join(config, "go-ten", "templates")
+}
+
+func TestTemplatesIn(t *testing.T) {
+	envDir, userDir := isolateTemplateDirs(t)
+
+	// The user directory adds a template and shadows an embedded one
+	writeTemplate(t, filepath.Join(userDir, "cli"), "cli", "cobra", map[string]string{"main.go": "package main\n"})
+	writeTemplate(t, filepath.Join(userDir, "api"), "web-api", "stdlib", map[string]string{"user.txt": "user"})
+	// $GO_TEN_TEMPLATE_PATH shadows the user directory
+	writeTemplate(t, filepath.Join(envDir, "api"), "web-api", "stdlib", map[string]string{"env.txt": "env"})
+
+	templates, err := TemplatesIn("")
+	if err != nil {
+		t.Fatalf("TemplatesIn failed: %v", err)
+	}
+	sources := map[string]string{}
+	for _, tmpl := range templates {
+		if _, ok := sources[tmpl.ID()]; ok {
+			t.Errorf("template %s listed twice", tmpl.ID())
+		}
+		sources[tmpl.ID()] = tmpl.Source
+	}
+	if sources["cli-cobra"] != filepath.Join(userDir, "cli") {
+		t.Errorf("cli-cobra source = %q", sources["cli-cobra"])
+	}
+	if sources["web-api-stdlib"] != filepath.Join(envDir, "api") {
+		t.Errorf("web-api-stdlib source = %q, want the $%s one", sources["web-api-stdlib"], TemplatePathEnv)
+	}
+
+	// An explicit directory with a single template comes first
+	explicit := t.TempDir()
+	writeTemplate(t, explicit, "web-api", "stdlib", map[string]string{"explicit.txt": "explicit"})
+	tmpl, err := FindTemplateIn(explicit, "web-api", "stdlib")
+	if err != nil {
+		t.Fatalf("FindTemplateIn failed: %v", err)
+	}
+	if tmpl.Source != explicit {
+		t.Errorf("FindTemplateIn source = %q, want %q", tmpl.Source, explicit)
+	}
+
+	// An explicit directory must exist
+	if _, err := TemplatesIn(filepath.Join(explicit, "missing")); err == nil {
+		t.Error("TemplatesIn should fail for a missing directory")
+	}
+}
+
+func TestTemplatesInEmbedded(t *testing.T) {
+	isolateTemplateDirs(t)
+
+	tmpl, err := FindTemplate("web-api", "stdlib")
+	if err != nil {
+		t.Fatalf("FindTemplate failed: %v", err)
+	}
+	if tmpl.Source != SourceEmbedded {
+		t.Errorf("Source = %q, want %q", tmpl.Source, SourceEmbedded)
+	}
+}
+
+func TestDryRunExternalTemplate(t *testing.T) {
+	isolateTemplateDirs(t)
+	dir := t.TempDir()
+	writeTemplate(t, dir, "worker", "stdlib", map[string]string{
+		"go.mod.tmpl":                  "module {{.ModuleName}}\n",
+		"cmd/{{.ProjectName}}/main.go": "package main\n",
+	})
+
+	config := ProjectConfig{ProjectName: "my-worker", ModuleName: "example.com/my-worker", AppType: "worker", Package: "stdlib", TargetDir: t.TempDir(), Template: dir}
+	plan, err := DryRun(config)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+
+	var paths []string
+	for _, entry := range plan.Files() {
+		paths = append(paths, entry.Path)
+	}
+	if len(paths) != 2 || paths[0] != "cmd/my-worker/main.go" || paths[1] != "go.mod" {
+		t.Errorf("Unexpected planned files: %v", paths)
+	}
+
+	// Without the directory the template is unknown
+	config.Template = ""
+	if _, err := DryRun(config); err == nil {
+		t.Error("DryRun should fail without the template directory")
+	}
+}
+
+func TestFindTemplateInGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	isolateTemplateDirs(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	// Publish a template to a local bare repository and tag it
+	work := t.TempDir()
+	remote := filepath.Join(t.TempDir(), "templates.git")
+	writeTemplate(t, work, "worker", "stdlib", map[string]string{"go.mod.tmpl": "module {{.ModuleName}}\n"})
+	for _, args := range [][]string{
+		{"init", "--quiet", "-b", "main"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "template"},
+		{"tag", "v1.0.0"},
+		{"clone", "--quiet", "--bare", work, remote},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = work
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	spec := "git+" + remote + "@v1.0.0"
+	tmpl, err := FindTemplateIn(spec, "worker", "stdlib")
+	if err != nil {
+		t.Fatalf("FindTemplateIn failed: %v", err)
+	}
+	if tmpl.Source != spec || tmpl.Commit == "" {
+		t.Errorf("Unexpected source %q and commit %q", tmpl.Source, tmpl.Commit)
+	}
+
+	config := ProjectConfig{ProjectName: "my-worker", ModuleName: "example.com/my-worker", AppType: "worker", Package: "stdlib", TargetDir: t.TempDir(), Template: spec}
+	plan, err := DryRun(config)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if files := plan.Files(); len(files) != 1 || files[0].Path != "go.mod" {
+		t.Errorf("Unexpected planned files: %+v", files)
+	}
+}
+
+func TestTemplatesInSkipsBrokenCache(t *testing.T) {
+	isolateTemplateDirs(t)
+	root, err := cache.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "templates.json"), []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var warnings bytes.Buffer
+	Warnings = &warnings
+	t.Cleanup(func() { Warnings = os.Stderr })
+
+	// The embedded templates are still found, with a warning about the cache
+	if _, err := FindTemplate("web-api", "stdlib"); err != nil {
+		t.Fatalf("FindTemplate failed: %v", err)
+	}
+	if !strings.Contains(warnings.String(), "Warning: skipping template cache: failed to parse template cache index") {
+		t.Errorf("Expected a warning about the cache, got %q", warnings.String())
+	}
+}
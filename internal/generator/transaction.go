@@ -0,0 +1,261 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// rename moves files into place; tests replace it to simulate failures
+var rename = os.Rename
+
+// transaction stages generated files next to the target directory and moves
+// them into place, undoing every change if anything fails
+type transaction struct {
+	targetDir     string // absolute target directory
+	targetExisted bool   // false if Generate creates the target itself
+	stagingDir    string // rendered plan is written here first
+	backupDir     string // overwritten files are moved here until commit succeeds
+
+	createdDirs   []string          // directories created in the target, in creation order
+	movedFiles    []string          // files moved into the target
+	backups       map[string]string // target path -> backup path of the replaced file
+	createdParent string            // topmost parent directory created for the target, if any
+}
+
+// Names of the work directories of a transaction, matched by the generated .gitignore
+const (
+	stagingPattern = ".go-ten-staging-*"
+	backupPattern  = ".go-ten-backup-*"
+)
+
+// newTransaction prepares a staging directory on the same filesystem as targetDir
+func newTransaction(targetDir string) (*transaction, error) {
+	absTarget, err := filepath.Abs(targetDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve target directory %s: %w", targetDir, err)
+	}
+	_, statErr := os.Stat(absTarget)
+	targetExisted := statErr == nil
+
+	// Stage next to the target rather than inside it, so an interrupted run
+	// leaves nothing behind in the project. The closest directory that already
+	// exists keeps the final moves renames within one filesystem.
+	existing := closestExisting(filepath.Dir(absTarget))
+	stagingDir, err := os.MkdirTemp(existing, stagingPattern)
+	if err != nil && targetExisted {
+		// The parent is not writable; fall back to the target, whose
+		// generated .gitignore hides the directory
+		stagingDir, err = os.MkdirTemp(absTarget, stagingPattern)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	return &transaction{
+		targetDir:     absTarget,
+		targetExisted: targetExisted,
+		stagingDir:    stagingDir,
+		backups:       map[string]string{},
+	}, nil
+}
+
+// closestExisting returns the absolute path dir, or its closest parent, that exists
+func closestExisting(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// commit applies the plan to the target directory. On error, or when ctx is
+// cancelled, everything the transaction changed is rolled back and the
+// staging area is removed.
+func (p *Plan) commit(ctx context.Context, targetDir string) (err error) {
+	tx, err := newTransaction(targetDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.rollback(); rbErr != nil {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+			}
+		}
+		tx.cleanup()
+	}()
+
+	// Render everything into the staging directory first
+	if err := p.write(ctx, tx.stagingDir); err != nil {
+		return fmt.Errorf("failed to copy template files: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !tx.targetExisted {
+		return tx.moveStagingDir()
+	}
+	if err := tx.moveEntries(ctx, p); err != nil {
+		return err
+	}
+	return tx.removeFiles(p.removals)
+}
+
+// moveStagingDir turns the staging directory into the new target directory
+func (tx *transaction) moveStagingDir() error {
+	// Create missing parents, remembering the topmost one for rollback
+	parent := filepath.Dir(tx.targetDir)
+	topmost := ""
+	for dir := parent; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		topmost = dir
+	}
+	tx.createdParent = topmost
+	if err := createDirectory(parent); err != nil {
+		return err
+	}
+
+	// MkdirTemp creates the staging directory as 0700
+	if err := os.Chmod(tx.stagingDir, dirMode.Perm()); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", tx.stagingDir, err)
+	}
+
+	if err := rename(tx.stagingDir, tx.targetDir); err != nil {
+		return fmt.Errorf("failed to move generated files into %s: %w", tx.targetDir, err)
+	}
+
+	return nil
+}
+
+// moveEntries moves each staged entry into an existing target directory,
+// backing up any file it replaces
+func (tx *transaction) moveEntries(ctx context.Context, p *Plan) error {
+	for _, entry := range p.Entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		stagedPath := filepath.Join(tx.stagingDir, filepath.FromSlash(entry.Path))
+		targetPath := filepath.Join(tx.targetDir, filepath.FromSlash(entry.Path))
+
+		if entry.IsDir {
+			if info, err := os.Stat(targetPath); err == nil && info.IsDir() {
+				continue
+			}
+			// A file overwritten by the directory is kept like replaced files
+			if _, err := os.Lstat(targetPath); err == nil {
+				if err := tx.backup(targetPath); err != nil {
+					return err
+				}
+			}
+			if err := os.Mkdir(targetPath, entry.Mode.Perm()); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", targetPath, err)
+			}
+			tx.createdDirs = append(tx.createdDirs, targetPath)
+			continue
+		}
+
+		// Keep the file being replaced until the whole transaction succeeds
+		if _, err := os.Lstat(targetPath); err == nil {
+			if err := tx.backup(targetPath); err != nil {
+				return err
+			}
+		}
+
+		if err := rename(stagedPath, targetPath); err != nil {
+			return fmt.Errorf("failed to move %s into place: %w", entry.Path, err)
+		}
+		tx.movedFiles = append(tx.movedFiles, targetPath)
+	}
+
+	return nil
+}
+
+// removeFiles moves the files at the slash separated paths out of the target
+// directory; they are deleted with the backups once the transaction succeeds
+func (tx *transaction) removeFiles(paths []string) error {
+	for _, p := range paths {
+		targetPath := filepath.Join(tx.targetDir, filepath.FromSlash(p))
+		if _, err := os.Lstat(targetPath); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := tx.backup(targetPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backup moves an existing target file out of the way
+func (tx *transaction) backup(targetPath string) error {
+	if tx.backupDir == "" {
+		backupDir, err := os.MkdirTemp(filepath.Dir(tx.stagingDir), backupPattern)
+		if err != nil {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		tx.backupDir = backupDir
+	}
+
+	backupPath := filepath.Join(tx.backupDir, fmt.Sprintf("%d", len(tx.backups)))
+	if err := rename(targetPath, backupPath); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", targetPath, err)
+	}
+	tx.backups[targetPath] = backupPath
+
+	return nil
+}
+
+// rollback undoes every change made to the target directory
+func (tx *transaction) rollback() error {
+	var errs []error
+
+	// Parent directories created by this transaction are removed entirely
+	if tx.createdParent != "" {
+		if err := os.RemoveAll(tx.createdParent); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Remove only the files this transaction added, newest first
+	for i := len(tx.movedFiles) - 1; i >= 0; i-- {
+		if err := os.Remove(tx.movedFiles[i]); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	// Remove created directories, deepest first, before putting back files
+	// they replaced
+	for i := len(tx.createdDirs) - 1; i >= 0; i-- {
+		if err := os.Remove(tx.createdDirs[i]); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	// Put replaced files back
+	for targetPath, backupPath := range tx.backups {
+		if err := os.Rename(backupPath, targetPath); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// cleanup removes the staging and backup directories
+func (tx *transaction) cleanup() {
+	os.RemoveAll(tx.stagingDir)
+	if tx.backupDir != "" {
+		os.RemoveAll(tx.backupDir)
+	}
+}
@@ -0,0 +1,200 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failRenameAfter makes the nth and later calls to rename fail
+func failRenameAfter(t *testing.T, n int) {
+	t.Helper()
+
+	calls := 0
+	rename = func(oldpath, newpath string) error {
+		calls++
+		if calls >= n {
+			return errors.New("simulated failure")
+		}
+		return os.Rename(oldpath, newpath)
+	}
+	t.Cleanup(func() { rename = os.Rename })
+}
+
+// listDir returns the names in dir
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestGenerateRollbackNewDirectory(t *testing.T) {
+	baseDir := t.TempDir()
+	failRenameAfter(t, 1)
+
+	config := ProjectConfig{
+		ProjectName: "test-project",
+		ModuleName:  "test-project",
+		AppType:     "web-api",
+		Package:     "stdlib",
+		TargetDir:   filepath.Join(baseDir, "parent", "test-project"),
+	}
+
+	if _, err := Generate(config); err == nil {
+		t.Fatal("Generate should have failed")
+	}
+
+	// Neither the target, its created parent nor the staging area may remain
+	if names := listDir(t, baseDir); len(names) != 0 {
+		t.Errorf("Expected empty base directory, found %v", names)
+	}
+}
+
+func TestGenerateRollbackCurrentDirectory(t *testing.T) {
+	testDir := t.TempDir()
+
+	existing := map[string]string{
+		"go.mod":    "module existing\n",
+		"README.md": "keep me\n",
+	}
+	for name, content := range existing {
+		if err := os.WriteFile(filepath.Join(testDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Fail halfway through moving files into place
+	failRenameAfter(t, 4)
+
+	config := ProjectConfig{
+		ProjectName:   "test-project",
+		ModuleName:    "test-project",
+		AppType:       "web-api",
+		Package:       "stdlib",
+		TargetDir:     testDir,
+		UseCurrentDir: true,
+		OnConflict:    ConflictOverwrite,
+	}
+
+	if _, err := Generate(config); err == nil {
+		t.Fatal("Generate should have failed")
+	}
+
+	// Only the pre-existing files remain, unchanged
+	names := listDir(t, testDir)
+	if len(names) != len(existing) {
+		t.Errorf("Expected only %d existing files, found %v", len(existing), names)
+	}
+	for name, content := range existing {
+		got, err := os.ReadFile(filepath.Join(testDir, name))
+		if err != nil || string(got) != content {
+			t.Errorf("%s was not restored: %q, %v", name, got, err)
+		}
+	}
+}
+
+func TestGenerateNewDirectoryPermissions(t *testing.T) {
+	testDir := filepath.Join(t.TempDir(), "test-project")
+
+	config := ProjectConfig{
+		ProjectName: "test-project",
+		ModuleName:  "test-project",
+		AppType:     "web-api",
+		Package:     "stdlib",
+		TargetDir:   testDir,
+	}
+
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	info, err := os.Stat(testDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("Expected target directory mode 0755, got %v", info.Mode().Perm())
+	}
+}
+
+func TestGenerateStagesOutsideCurrentDirectory(t *testing.T) {
+	testDir := filepath.Join(t.TempDir(), "test-project")
+	if err := os.Mkdir(testDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Record where files are moved from
+	var sources []string
+	rename = func(oldpath, newpath string) error {
+		sources = append(sources, oldpath)
+		return os.Rename(oldpath, newpath)
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	config := ProjectConfig{
+		ProjectName:   "test-project",
+		ModuleName:    "test-project",
+		AppType:       "web-api",
+		Package:       "stdlib",
+		TargetDir:     testDir,
+		UseCurrentDir: true,
+		Git:           GitOptions{Init: true},
+	}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Staging happens next to the project, never inside it
+	for _, source := range sources {
+		if rel, err := filepath.Rel(testDir, source); err == nil && filepath.IsLocal(rel) {
+			t.Errorf("Staged %s inside the project", source)
+		}
+	}
+	if len(sources) == 0 {
+		t.Error("Expected files to be moved into place")
+	}
+
+	// The work directories are ignored in case a run is interrupted
+	gitignore, err := os.ReadFile(filepath.Join(testDir, ".gitignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pattern := range []string{"/.go-ten-staging-*/", "/.go-ten-backup-*/"} {
+		if !strings.Contains(string(gitignore), pattern+"\n") {
+			t.Errorf("Expected %s in .gitignore:\n%s", pattern, gitignore)
+		}
+	}
+}
+
+func TestGenerateContextCancelled(t *testing.T) {
+	baseDir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	config := ProjectConfig{
+		ProjectName: "test-project",
+		ModuleName:  "test-project",
+		AppType:     "web-api",
+		Package:     "stdlib",
+		TargetDir:   filepath.Join(baseDir, "test-project"),
+	}
+	if _, err := GenerateContext(ctx, config); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	// Nothing was written, not even the staging area
+	if names := listDir(t, baseDir); len(names) != 0 {
+		t.Errorf("Expected empty base directory, found %v", names)
+	}
+}
@@ -0,0 +1,305 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/manuelbamise/go-ten/internal/cache"
+	"github.com/manuelbamise/go-ten/internal/diff"
+)
+
+// UpdateStatus says what Update did with a file
+type UpdateStatus string
+
+const (
+	UpdateUnchanged UpdateStatus = "unchanged" // nothing to do, local changes are kept
+	UpdateUpdated   UpdateStatus = "updated"   // not modified locally, replaced by the new template output
+	UpdateMerged    UpdateStatus = "merged"    // local and template changes merged cleanly
+	UpdateConflict  UpdateStatus = "conflict"  // written with conflict markers
+	UpdateAdded     UpdateStatus = "added"     // new in the template
+	UpdateRemoved   UpdateStatus = "removed"   // dropped by the template and not modified locally
+	UpdateKept      UpdateStatus = "kept"      // left alone, see Note
+)
+
+// FileUpdate is the outcome of Update for one file
+type FileUpdate struct {
+	Path      string       `json:"path"`
+	Status    UpdateStatus `json:"status"`
+	Conflicts int          `json:"conflicts,omitempty"`
+	Note      string       `json:"note,omitempty"`
+}
+
+// UpdateOptions selects the project and template versions for Update
+type UpdateOptions struct {
+	Dir      string // project directory holding the lockfile
+	Template string // template source to update to; defaults to the one in the lockfile
+	From     string // template source of the version the project was generated from; derived from the lockfile when empty
+	DryRun   bool   // report without writing
+}
+
+// UpdateResult reports what Update did
+type UpdateResult struct {
+	From  LockTemplate `json:"from"`
+	To    LockTemplate `json:"to"`
+	Files []FileUpdate `json:"files"`
+}
+
+// Conflicts returns the number of conflicts left in the project
+func (r *UpdateResult) Conflicts() int {
+	n := 0
+	for _, f := range r.Files {
+		n += f.Conflicts
+	}
+	return n
+}
+
+// Update re-applies the template of a generated project. The old and new
+// template versions are rendered with the answers from the lockfile and
+// three-way merged into the project files: files not modified locally take
+// the new output, local changes are kept, and changes on both sides are
+// merged, with conflict markers where they overlap.
+func Update(opts UpdateOptions) (*UpdateResult, error) {
+	lock, err := ReadLock(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	config := lock.Config
+	config.TargetDir = opts.Dir
+
+	// Render the new template version
+	newConfig := config
+	if opts.Template != "" {
+		newConfig.Template = opts.Template
+	}
+	newPlan, err := renderForUpdate(newConfig)
+	if err != nil {
+		return nil, err
+	}
+	theirs := newPlan.Contents()
+
+	// The common base is the copy of the old output kept in the project.
+	// Files missing from it are rendered from the old version; only those
+	// matching the recorded hashes are trusted.
+	base := readBase(opts.Dir, lock)
+	if opts.From != "" || len(base) < len(lock.Files) {
+		baseConfig := config
+		baseConfig.Template = opts.From
+		if baseConfig.Template == "" {
+			baseConfig.Template = baseSource(lock)
+		}
+		if basePlan, err := renderForUpdate(baseConfig); err == nil {
+			for p, content := range basePlan.Contents() {
+				if _, ok := base[p]; !ok && HashContent(content) == lock.Files[p] {
+					base[p] = content
+				}
+			}
+		} else if opts.From != "" {
+			return nil, fmt.Errorf("failed to render the old template: %w", err)
+		}
+	}
+
+	result := &UpdateResult{From: lock.Template, To: newPlan.lock().Template}
+	theirsLabel := "template"
+	if result.To.Version != "" {
+		theirsLabel += " " + result.To.Version
+	}
+
+	// Every file generated before or now
+	paths := make([]string, 0, len(theirs))
+	for p := range theirs {
+		paths = append(paths, p)
+	}
+	for p := range lock.Files {
+		if _, ok := theirs[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	write := &Plan{TargetDir: opts.Dir}
+	for _, p := range paths {
+		update, content, err := updateFile(opts.Dir, p, lock, base, theirs, theirsLabel)
+		if err != nil {
+			return nil, err
+		}
+		if update.Status == "" {
+			continue
+		}
+		result.Files = append(result.Files, update)
+
+		switch update.Status {
+		case UpdateUpdated, UpdateMerged, UpdateConflict, UpdateAdded:
+			write.addParents(p)
+			write.addFile(p, p, content)
+		case UpdateRemoved:
+			write.removals = append(write.removals, p)
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	// The new lockfile records the new template output, so files merged now
+	// still count as modified locally next time. What the post-generation
+	// steps made of output the template did not change stays generated.
+	newLock := newPlan.lock()
+	for p, hash := range lock.PostStepFiles {
+		if newLock.Files[p] == lock.Files[p] {
+			if newLock.PostStepFiles == nil {
+				newLock.PostStepFiles = map[string]string{}
+			}
+			newLock.PostStepFiles[p] = hash
+		}
+	}
+	lockData, err := newLock.encode()
+	if err != nil {
+		return nil, err
+	}
+	write.addFile(LockFile, "", lockData)
+
+	// The copy of the output becomes the base of the next update
+	for _, p := range paths {
+		content, ok := theirs[p]
+		if !ok {
+			write.removals = append(write.removals, basePath(p))
+			continue
+		}
+		if old, ok := base[p]; !ok || !bytes.Equal(old, content) {
+			write.addParents(basePath(p))
+			write.addFile(basePath(p), p, content)
+		}
+	}
+
+	if err := write.commit(context.Background(), opts.Dir); err != nil {
+		return nil, err
+	}
+	newPlan.template.markUsed()
+
+	return result, nil
+}
+
+// updateFile decides what happens to the file at p and returns its new content
+func updateFile(dir, p string, lock *Lock, base, theirs map[string][]byte, theirsLabel string) (FileUpdate, []byte, error) {
+	update := FileUpdate{Path: p}
+
+	ours, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(p)))
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return update, nil, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	hash, wasGenerated := lock.Files[p]
+	unmodified := exists && wasGenerated && lock.Generated(p, ours)
+	newContent, inTemplate := theirs[p]
+
+	switch {
+	case !inTemplate:
+		if !exists {
+			return FileUpdate{}, nil, nil
+		}
+		if unmodified {
+			update.Status = UpdateRemoved
+			return update, nil, nil
+		}
+		update.Status, update.Note = UpdateKept, "removed from the template but modified locally"
+		return update, nil, nil
+
+	case !exists:
+		if wasGenerated {
+			update.Status, update.Note = UpdateKept, "deleted locally"
+			return update, nil, nil
+		}
+		update.Status = UpdateAdded
+		return update, newContent, nil
+
+	case bytes.Equal(ours, newContent):
+		update.Status = UpdateUnchanged
+		return update, nil, nil
+
+	case wasGenerated && HashContent(newContent) == hash:
+		// Only changed locally or by the post-generation steps
+		update.Status = UpdateUnchanged
+		return update, nil, nil
+
+	case unmodified:
+		update.Status = UpdateUpdated
+		return update, newContent, nil
+	}
+
+	// Changed on both sides
+	if baseContent, ok := base[p]; ok {
+		merged, conflicts := diff.Merge(string(baseContent), string(ours), string(newContent), "local", theirsLabel)
+		update.Status, update.Conflicts = UpdateMerged, conflicts
+		if conflicts > 0 {
+			update.Status = UpdateConflict
+		}
+		return update, []byte(merged), nil
+	}
+
+	// Without the old output there is nothing to merge against
+	update.Status, update.Conflicts = UpdateConflict, 1
+	if wasGenerated {
+		update.Note = "old template version unavailable"
+	} else {
+		update.Note = "file existed before the template added it"
+	}
+	return update, []byte(diff.Conflict(string(ours), string(newContent), "local", theirsLabel)), nil
+}
+
+// renderForUpdate renders config, passing only the variables the template declares
+func renderForUpdate(config ProjectConfig) (*Plan, error) {
+	tmpl, err := FindTemplateIn(config.Template, config.AppType, config.Package)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]any{}
+	for _, v := range tmpl.Variables {
+		if value, ok := config.Vars[v.Name]; ok {
+			vars[v.Name] = value
+		}
+	}
+	config.Vars = vars
+
+	return render(config)
+}
+
+// baseSource returns the template source to render the version recorded in
+// the lockfile from. Git sources are pinned to the recorded commit.
+func baseSource(lock *Lock) string {
+	if lock.Template.Commit != "" && cache.IsGitSource(lock.Template.Source) {
+		if source, err := cache.ParseGitSource(lock.Template.Source); err == nil {
+			source.Ref = lock.Template.Commit
+			return source.String()
+		}
+	}
+	return lock.Config.Template
+}
+
+// addParents adds the directories above the slash separated file path
+func (p *Plan) addParents(file string) {
+	var dirs []string
+	for dir := path.Dir(file); dir != "."; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		found := false
+		for _, entry := range p.Entries {
+			if entry.IsDir && entry.Path == dirs[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			p.addDir(dirs[i], dirs[i])
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	isolateTemplateDirs(t)
+	v1 := t.TempDir()
+	writeTemplate(t, v1, "tool", "plain", map[string]string{
+		"clean.txt.tmpl":  "{{.ProjectName}} v1\n",
+		"merge.txt":       "one\ntwo\nthree\nfour\nfive\n",
+		"conflict.txt":    "setting = 1\n",
+		"local.txt":       "generated\n",
+		"deleted.txt":     "delete me\n",
+		"dropped.txt":     "dropped\n",
+		"dropped-mod.txt": "dropped\n",
+	})
+	v2 := t.TempDir()
+	writeTemplate(t, v2, "tool", "plain", map[string]string{
+		"clean.txt.tmpl":  "{{.ProjectName}} v2\n",
+		"merge.txt":       "one\ntwo\nthree\nfour\nFIVE\n",
+		"conflict.txt":    "setting = 2\n",
+		"local.txt":       "generated\n",
+		"deleted.txt":     "delete me too\n",
+		"pkg/new/new.txt": "new\n",
+	})
+
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	config := ProjectConfig{ProjectName: "svc", AppType: "tool", Package: "plain", TargetDir: targetDir, Template: v1}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Local changes
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(targetDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("merge.txt", "ONE\ntwo\nthree\nfour\nfive\n")
+	write("conflict.txt", "setting = 3\n")
+	write("local.txt", "edited\n")
+	write("dropped-mod.txt", "edited\n")
+	if err := os.Remove(filepath.Join(targetDir, "deleted.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A dry run reports without writing
+	lockBefore, _ := os.ReadFile(filepath.Join(targetDir, LockFile))
+	if _, err := Update(UpdateOptions{Dir: targetDir, Template: v2, From: v1, DryRun: true}); err != nil {
+		t.Fatalf("Update dry run failed: %v", err)
+	}
+	if lockAfter, _ := os.ReadFile(filepath.Join(targetDir, LockFile)); string(lockAfter) != string(lockBefore) {
+		t.Error("Dry run rewrote the lockfile")
+	}
+
+	result, err := Update(UpdateOptions{Dir: targetDir, Template: v2, From: v1})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	statuses := map[string]UpdateStatus{}
+	for _, f := range result.Files {
+		statuses[f.Path] = f.Status
+	}
+	want := map[string]UpdateStatus{
+		"clean.txt":       UpdateUpdated,
+		"merge.txt":       UpdateMerged,
+		"conflict.txt":    UpdateConflict,
+		"local.txt":       UpdateUnchanged,
+		"deleted.txt":     UpdateKept,
+		"dropped.txt":     UpdateRemoved,
+		"dropped-mod.txt": UpdateKept,
+		"pkg/new/new.txt": UpdateAdded,
+	}
+	for path, status := range want {
+		if statuses[path] != status {
+			t.Errorf("%s: status %q, want %q", path, statuses[path], status)
+		}
+	}
+	if result.Conflicts() != 1 {
+		t.Errorf("Conflicts() = %d, want 1", result.Conflicts())
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(targetDir, filepath.FromSlash(name)))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+	files := map[string]string{
+		"clean.txt":       "svc v2\n",
+		"merge.txt":       "ONE\ntwo\nthree\nfour\nFIVE\n",
+		"conflict.txt":    "<<<<<<< local\nsetting = 3\n=======\nsetting = 2\n>>>>>>> template\n",
+		"local.txt":       "edited\n",
+		"deleted.txt":     "<missing>",
+		"dropped.txt":     "<missing>",
+		"dropped-mod.txt": "edited\n",
+		"pkg/new/new.txt": "new\n",
+	}
+	for name, content := range files {
+		if got := read(name); got != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+
+	// The lockfile now records the new template output
+	lock, err := ReadLock(targetDir)
+	if err != nil {
+		t.Fatalf("ReadLock failed: %v", err)
+	}
+	if lock.Config.Template != v2 || lock.Template.Source != v2 {
+		t.Errorf("Lockfile still points at the old template: %+v", lock.Template)
+	}
+	if lock.Files["conflict.txt"] != HashContent([]byte("setting = 2\n")) {
+		t.Error("Lockfile should hash the template output, not the merged file")
+	}
+	if _, ok := lock.Files["dropped.txt"]; ok {
+		t.Error("Lockfile still lists a dropped file")
+	}
+}
+
+func TestUpdateWithoutBase(t *testing.T) {
+	isolateTemplateDirs(t)
+	tmplDir := t.TempDir()
+	writeTemplate(t, tmplDir, "tool", "plain", map[string]string{"a.txt": "one\n", "b.txt": "one\n"})
+
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	config := ProjectConfig{ProjectName: "svc", AppType: "tool", Package: "plain", TargetDir: targetDir, Template: tmplDir}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// The template changes in place, so the old version cannot be rendered,
+	// and the project lost its copy of the old output
+	writeTemplate(t, tmplDir, "tool", "plain", map[string]string{"a.txt": "two\n", "b.txt": "two\n"})
+	if err := os.RemoveAll(filepath.Join(targetDir, ".go-ten")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(targetDir, "b.txt"), []byte("local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Update(UpdateOptions{Dir: targetDir})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	for _, f := range result.Files {
+		switch f.Path {
+		case "a.txt":
+			if f.Status != UpdateUpdated {
+				t.Errorf("a.txt: status %q, want %q", f.Status, UpdateUpdated)
+			}
+		case "b.txt":
+			if f.Status != UpdateConflict || f.Note == "" {
+				t.Errorf("b.txt: %+v, want a conflict with a note", f)
+			}
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(targetDir, "b.txt"))
+	if !strings.Contains(string(data), "<<<<<<< local\nlocal\n=======\ntwo\n>>>>>>> template\n") {
+		t.Errorf("b.txt has no conflict markers:\n%s", data)
+	}
+
+	// Without a lockfile there is nothing to update
+	if _, err := Update(UpdateOptions{Dir: t.TempDir()}); err == nil {
+		t.Error("Update should fail without a lockfile")
+	}
+}
+
+func TestUpdateFromBaseCopy(t *testing.T) {
+	isolateTemplateDirs(t)
+	tmplDir := t.TempDir()
+	writeTemplate(t, tmplDir, "tool", "plain", map[string]string{
+		"merge.txt":   "one\ntwo\nthree\n",
+		"dropped.txt": "dropped\n",
+	})
+
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	config := ProjectConfig{ProjectName: "svc", AppType: "tool", Package: "plain", TargetDir: targetDir, Template: tmplDir}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(targetDir, BaseDir, "merge.txt")); err != nil || string(data) != "one\ntwo\nthree\n" {
+		t.Fatalf("Expected a copy of the output, got %q, %v", data, err)
+	}
+
+	// The template changes in place; the copy still allows a merge
+	writeTemplate(t, tmplDir, "tool", "plain", map[string]string{"merge.txt": "one\ntwo\nTHREE\n"})
+	if err := os.Remove(filepath.Join(tmplDir, "dropped.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(targetDir, "merge.txt"), []byte("ONE\ntwo\nthree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Update(UpdateOptions{Dir: targetDir})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if result.Conflicts() != 0 {
+		t.Errorf("Expected a clean merge, got %+v", result.Files)
+	}
+	if data, _ := os.ReadFile(filepath.Join(targetDir, "merge.txt")); string(data) != "ONE\ntwo\nTHREE\n" {
+		t.Errorf("Unexpected merge result:\n%s", data)
+	}
+
+	// The copy follows the new output
+	if data, _ := os.ReadFile(filepath.Join(targetDir, BaseDir, "merge.txt")); string(data) != "one\ntwo\nTHREE\n" {
+		t.Errorf("Copy not updated: %q", data)
+	}
+	for _, name := range []string{"dropped.txt", BaseDir + "/dropped.txt"} {
+		if _, err := os.Stat(filepath.Join(targetDir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got %v", name, err)
+		}
+	}
+}
+
+func TestUpdateRollsBackRemovals(t *testing.T) {
+	isolateTemplateDirs(t)
+	tmplDir := t.TempDir()
+	writeTemplate(t, tmplDir, "tool", "plain", map[string]string{"a.txt": "a\n", "dropped.txt": "dropped\n"})
+
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	config := ProjectConfig{ProjectName: "svc", AppType: "tool", Package: "plain", TargetDir: targetDir, Template: tmplDir}
+	if _, err := Generate(config); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// Writing the lockfile succeeds, removing the dropped file fails
+	if err := os.Remove(filepath.Join(tmplDir, "dropped.txt")); err != nil {
+		t.Fatal(err)
+	}
+	failRenameAfter(t, 3)
+	if _, err := Update(UpdateOptions{Dir: targetDir}); err == nil {
+		t.Fatal("Update should have failed")
+	}
+	if data, err := os.ReadFile(filepath.Join(targetDir, "dropped.txt")); err != nil || string(data) != "dropped\n" {
+		t.Errorf("dropped.txt was not restored: %q, %v", data, err)
+	}
+	if lock, err := ReadLock(targetDir); err != nil || lock.Files["dropped.txt"] == "" {
+		t.Errorf("The lockfile was not restored: %+v, %v", lock, err)
+	}
+}
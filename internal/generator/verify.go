@@ -0,0 +1,249 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/manuelbamise/go-ten/internal/commands"
+)
+
+// VerifyLevel selects how thoroughly generated code is checked
+type VerifyLevel string
+
+const (
+	VerifyNone   VerifyLevel = "none"   // no checks
+	VerifySyntax VerifyLevel = "syntax" // parse and gofmt check every generated .go file
+	VerifyVet    VerifyLevel = "vet"    // syntax, then go vet ./...
+	VerifyBuild  VerifyLevel = "build"  // vet, then go build ./...
+)
+
+// VerifyLevels lists the valid levels
+var VerifyLevels = []VerifyLevel{VerifyNone, VerifySyntax, VerifyVet, VerifyBuild}
+
+// verifyTimeout limits go vet and go build in the new project
+const verifyTimeout = 5 * time.Minute
+
+// ParseVerifyLevel converts a level name to a VerifyLevel
+func ParseVerifyLevel(name string) (VerifyLevel, error) {
+	for _, level := range VerifyLevels {
+		if string(level) == name {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("invalid verify level %q (valid: none, syntax, vet, build)", name)
+}
+
+// Issue is a problem found in the generated code or in a template
+type Issue struct {
+	File    string `json:"file,omitempty"` // slash separated, relative to the project or template; empty when unknown
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the issue as file:line:col: message
+func (i Issue) String() string {
+	switch {
+	case i.File == "":
+		return i.Message
+	case i.Line == 0:
+		return fmt.Sprintf("%s: %s", i.File, i.Message)
+	case i.Column == 0:
+		return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", i.File, i.Line, i.Column, i.Message)
+	}
+}
+
+// VerifyError is returned when the generated code has problems
+type VerifyError struct {
+	Issues []Issue
+}
+
+func (e *VerifyError) Error() string {
+	lines := make([]string, 0, len(e.Issues)+1)
+	lines = append(lines, fmt.Sprintf("generated code has %d problem(s):", len(e.Issues)))
+	for _, issue := range e.Issues {
+		lines = append(lines, "  "+issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Verify checks the Go files generated into config.TargetDir at config.Verify
+// level and returns a *VerifyError listing every problem found. Output of go
+// vet and go build is also written to out.
+func Verify(ctx context.Context, config ProjectConfig, out io.Writer) error {
+	if config.Verify == "" || config.Verify == VerifyNone {
+		return nil
+	}
+
+	// Only the files go-ten generated are checked, as recorded in the lockfile
+	lock, err := ReadLock(config.TargetDir)
+	if err != nil {
+		return err
+	}
+	var files []string
+	for path := range lock.Files {
+		if strings.HasSuffix(path, ".go") {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+
+	// Files the manifest keeps as rendered only need to parse. The template
+	// is rendered with the answers recorded in the lockfile, like update and
+	// diff do.
+	recorded := lock.Config
+	recorded.TargetDir = config.TargetDir
+	plan, err := renderForUpdate(recorded)
+	if err != nil {
+		return err
+	}
+	rendered := map[string]PlanEntry{}
+	for _, entry := range plan.Files() {
+		rendered[entry.Path] = entry
+	}
+
+	var issues []Issue
+	for _, path := range files {
+		content, err := os.ReadFile(filepath.Join(config.TargetDir, filepath.FromSlash(path)))
+		if errors.Is(err, os.ErrNotExist) {
+			// Skipped on conflict or removed by a post-generation step
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		entry := rendered[path]
+		if !plan.template.formatted(entry.Source) {
+			issues = append(issues, parseSource(path, content)...)
+			continue
+		}
+		fileIssues := checkSource(path, content)
+		if len(fileIssues) == 0 && entry.FormatError != "" && bytes.Equal(content, entry.content) {
+			// Parses, but formatting it during generation failed
+			fileIssues = []Issue{{File: path, Message: entry.FormatError}}
+		}
+		issues = append(issues, fileIssues...)
+	}
+
+	// The go tool only makes sense for code that parses
+	var checks []string
+	switch config.Verify {
+	case VerifyVet:
+		checks = []string{"vet"}
+	case VerifyBuild:
+		checks = []string{"vet", "build"}
+	}
+	for _, check := range checks {
+		if len(issues) > 0 {
+			break
+		}
+		goIssues, err := runGoCheck(ctx, config.TargetDir, out, check)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, goIssues...)
+	}
+
+	if len(issues) > 0 {
+		return &VerifyError{Issues: issues}
+	}
+	return nil
+}
+
+// checkSource parses a Go file and checks that it is gofmt formatted
+func checkSource(path string, content []byte) []Issue {
+	if issues := parseSource(path, content); len(issues) > 0 {
+		return issues
+	}
+
+	formatted, err := format.Source(content)
+	if err != nil {
+		return []Issue{{File: path, Message: err.Error()}}
+	}
+	if !bytes.Equal(formatted, content) {
+		return []Issue{{File: path, Line: firstDifference(content, formatted), Message: "not gofmt formatted"}}
+	}
+	return nil
+}
+
+// parseSource parses a Go file and returns its syntax errors
+func parseSource(path string, content []byte) []Issue {
+	fset := token.NewFileSet()
+	_, err := parser.ParseFile(fset, path, content, parser.AllErrors)
+	if err == nil {
+		return nil
+	}
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return []Issue{{File: path, Message: err.Error()}}
+	}
+
+	// Like the compiler, report only the first error of each line
+	list.RemoveMultiples()
+	var issues []Issue
+	for _, e := range list {
+		issues = append(issues, Issue{File: path, Line: e.Pos.Line, Column: e.Pos.Column, Message: e.Msg})
+	}
+	return issues
+}
+
+// firstDifference returns the first line that differs between a and b
+func firstDifference(a, b []byte) int {
+	aLines := bytes.Split(a, []byte("\n"))
+	bLines := bytes.Split(b, []byte("\n"))
+	for i := range min(len(aLines), len(bLines)) {
+		if !bytes.Equal(aLines[i], bLines[i]) {
+			return i + 1
+		}
+	}
+	return min(len(aLines), len(bLines))
+}
+
+// goPosition matches the positions the go tool reports, e.g. "./cmd/main.go:12:3: ..."
+var goPosition = regexp.MustCompile(`^(?:vet: )?(?:\./)?([^\s:]+\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// runGoCheck runs "go <check> ./..." in dir and turns its complaints into
+// issues. It only fails when the check could not run to the end.
+func runGoCheck(ctx context.Context, dir string, out io.Writer, check string) ([]Issue, error) {
+	cmd := commands.Command{Name: "go", Args: []string{check, "./..."}, Dir: dir, Timeout: verifyTimeout, Stdout: out, Stderr: out}
+	fmt.Fprintf(out, "$ %s\n", cmd)
+	result, err := commands.Run(ctx, cmd)
+	if err == nil {
+		return nil, nil
+	}
+	if result == nil || ctx.Err() != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, line := range strings.Split(result.Stderr, "\n") {
+		match := goPosition.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		issues = append(issues, Issue{File: filepath.ToSlash(match[1]), Line: lineNo, Column: column, Message: match[4]})
+	}
+	if len(issues) == 0 {
+		// Nothing points at a file, e.g. a missing dependency
+		issues = append(issues, Issue{Message: err.Error()})
+	}
+	return issues, nil
+}
@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	isolateTemplateDirs(t)
+
+	templateDir := t.TempDir()
+	writeTemplate(t, templateDir, "worker", "stdlib", map[string]string{
+		"go.mod.tmpl":           "module {{.ModuleName}}\n\ngo 1.21\n",
+		"main.go.tmpl":          "package main\n\nimport \"{{.ModuleName}}/internal/jobs\"\n\nfunc main() {\n\tjobs.Run()\n}\n",
+		"internal/jobs/jobs.go": "package jobs\n\n// Run runs the jobs\nfunc Run() {}\n",
+		"notes.txt":             "not Go\n",
+	})
+
+	generate := func(t *testing.T, level VerifyLevel) ProjectConfig {
+		t.Helper()
+		config, err := NewProjectConfig("my-worker", "worker", "stdlib", filepath.Join(t.TempDir(), "svc"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.Template = templateDir
+		config.Verify = level
+		if _, err := Generate(config); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		return config
+	}
+	issues := func(err error) []string {
+		var verifyErr *VerifyError
+		if !errors.As(err, &verifyErr) {
+			t.Fatalf("Expected a *VerifyError, got %v", err)
+		}
+		var lines []string
+		for _, issue := range verifyErr.Issues {
+			lines = append(lines, issue.String())
+		}
+		return lines
+	}
+
+	// Valid, formatted code passes
+	if err := Verify(context.Background(), generate(t, VerifySyntax), io.Discard); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+
+	// Syntax errors and unformatted code are reported by file and line,
+	// except for the formatting of files the manifest keeps as rendered
+	writeTemplate(t, templateDir, "worker", "stdlib", map[string]string{
+		"main.go.tmpl":          "package main\n\nfunc main() {\n\tx :=\n}\n",
+		"util.go":               "package main\n\nfunc util() {}\n",
+		"internal/jobs/jobs.go": "package jobs\n\nfunc Run()   {}\n",
+	})
+	manifest := "name: worker\nappType: worker\npackage: stdlib\nfiles:\n  - paths: [internal/jobs]\n    format: false\n"
+	if err := os.WriteFile(filepath.Join(templateDir, ManifestFile), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := generate(t, VerifySyntax)
+	if err := os.WriteFile(filepath.Join(config.TargetDir, "util.go"), []byte("package main\n\nfunc util()   {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := issues(Verify(context.Background(), config, io.Discard))
+	want := []string{
+		"main.go:5:1: expected operand, found '}'",
+		"util.go:3: not gofmt formatted",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// go build finds what parsing cannot
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	t.Setenv("GOFLAGS", "-mod=mod")
+	writeTemplate(t, templateDir, "worker", "stdlib", map[string]string{
+		"main.go.tmpl":          "package main\n\nfunc main() {\n\tundefinedFunc()\n}\n",
+		"internal/jobs/jobs.go": "package jobs\n",
+	})
+	got = issues(Verify(context.Background(), generate(t, VerifyBuild), io.Discard))
+	if len(got) != 1 || !strings.HasPrefix(got[0], "main.go:4:2: undefined: undefinedFunc") {
+		t.Errorf("Expected the undefined function to be reported, got %v", got)
+	}
+}
+
+func TestParseVerifyLevel(t *testing.T) {
+	for _, level := range VerifyLevels {
+		if got, err := ParseVerifyLevel(string(level)); err != nil || got != level {
+			t.Errorf("ParseVerifyLevel(%q) = %q, %v", level, got, err)
+		}
+	}
+	if _, err := ParseVerifyLevel("lint"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
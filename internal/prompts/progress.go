@@ -0,0 +1,369 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+// Default size of the log viewport until the terminal size is known
+const (
+	logWidth  = 80
+	logHeight = 10
+)
+
+// taskStatus is the state of one task on the progress screen
+type taskStatus int
+
+const (
+	taskPending taskStatus = iota
+	taskRunning
+	taskDone
+	taskFailed
+	taskSkipped
+)
+
+// task is a unit of work shown on the progress screen: generating the files,
+// then each post-generation step, verifying the code and creating the git
+// repository
+type task struct {
+	label  string
+	name   string                                                     // reported when the task fails
+	run    func(ctx context.Context, dir string, out io.Writer) error // nil for generating the files
+	status taskStatus
+	err    error
+}
+
+// stepTask returns the task running a post-generation step
+func stepTask(step generator.PostStep) task {
+	return task{
+		label: step.Run,
+		name:  step.Name,
+		run: func(ctx context.Context, dir string, out io.Writer) error {
+			cmd, err := step.Command(dir)
+			if err != nil {
+				return err
+			}
+			cmd.Stdout, cmd.Stderr = out, out
+
+			fmt.Fprintf(out, "$ %s\n", cmd)
+			return generator.RunPostStep(ctx, cmd)
+		},
+	}
+}
+
+// verifyTask returns the task checking the generated code of config
+func verifyTask(config generator.ProjectConfig) task {
+	return task{
+		label: fmt.Sprintf("Verify generated code (%s)", config.Verify),
+		name:  "verify",
+		run: func(ctx context.Context, dir string, out io.Writer) error {
+			return generator.Verify(ctx, config, out)
+		},
+	}
+}
+
+// gitTask returns the task creating the git repository
+func gitTask(opts generator.GitOptions) task {
+	return task{
+		label: "Initialize git repository",
+		name:  "git",
+		run: func(ctx context.Context, dir string, out io.Writer) error {
+			_, err := generator.InitGit(ctx, dir, opts, out)
+			return err
+		},
+	}
+}
+
+// generatedMsg reports that the project files were written
+type generatedMsg struct {
+	plan *generator.Plan
+	err  error
+}
+
+// stepDoneMsg reports that task index finished
+type stepDoneMsg struct {
+	index int
+	err   error
+}
+
+// logMsg carries output of the running task
+type logMsg string
+
+// logWriter sends command output to the progress screen
+type logWriter struct {
+	ctx context.Context
+	ch  chan<- string
+}
+
+// Write passes p on, dropping it once the progress screen stopped listening
+func (w logWriter) Write(p []byte) (int, error) {
+	select {
+	case w.ch <- string(p):
+	case <-w.ctx.Done():
+	}
+	return len(p), nil
+}
+
+// startProgress moves to the progress stage and starts generating config,
+// followed by its post-generation steps, verification and the git repository
+func (m Model) startProgress(config generator.ProjectConfig) (tea.Model, tea.Cmd) {
+	steps, err := generator.PostStepsFor(config)
+	if err != nil {
+		m.generationError = err
+		return m, nil
+	}
+
+	m.tasks = []task{{label: "Generate project files", status: taskRunning}}
+	for _, step := range steps {
+		m.tasks = append(m.tasks, stepTask(step))
+	}
+	if config.Verify != "" && config.Verify != generator.VerifyNone {
+		m.tasks = append(m.tasks, verifyTask(config))
+	}
+	if config.Git.Init {
+		m.tasks = append(m.tasks, gitTask(config.Git))
+	}
+
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.logCh = make(chan string, 64)
+	m.log = ""
+	m.cancelled = false
+	m.spinner = spinner.New(spinner.WithSpinner(spinner.Dot))
+	m.logView = viewport.New(m.logWidth(), m.logHeight())
+	m.generationError = nil
+	m.currentStage = StageProgress
+
+	return m, tea.Batch(m.spinner.Tick, generateCmd(m.ctx, config), waitForLog(m.ctx, m.logCh))
+}
+
+// generateCmd writes the project files; cancelling ctx undoes them
+func generateCmd(ctx context.Context, config generator.ProjectConfig) tea.Cmd {
+	return func() tea.Msg {
+		plan, err := generator.GenerateContext(ctx, config)
+		return generatedMsg{plan: plan, err: err}
+	}
+}
+
+// runTaskCmd runs task index in dir, sending its output to the log
+func runTaskCmd(ctx context.Context, index int, t task, dir string, log chan<- string) tea.Cmd {
+	return func() tea.Msg {
+		err := t.run(ctx, dir, logWriter{ctx: ctx, ch: log})
+		return stepDoneMsg{index: index, err: err}
+	}
+}
+
+// waitForLog waits for the next output of a task
+func waitForLog(ctx context.Context, log <-chan string) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case chunk := <-log:
+			return logMsg(chunk)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// updateProgress handles messages while generating and running the steps
+func (m Model) updateProgress(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		// Cancelling kills the running step; the screen stays until it exits
+		if msg.String() == "ctrl+c" || msg.String() == "q" {
+			m.cancelled = true
+			m.cancel()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.logView, cmd = m.logView.Update(msg)
+		return m, cmd
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case logMsg:
+		m.log += string(msg)
+		m.logView.SetContent(m.log)
+		m.logView.GotoBottom()
+		return m, waitForLog(m.ctx, m.logCh)
+
+	case generatedMsg:
+		if msg.err != nil {
+			m.cancel()
+			m.generationError = msg.err
+			if m.cancelled {
+				m.quitting = true
+				return m, tea.Quit
+			}
+			// Back to the summary, which offers a retry
+			m.currentStage = StageSummary
+			return m, nil
+		}
+		m.generationSuccess = true
+		m.tasks = setTask(m.tasks, 0, taskDone, nil)
+		for _, warning := range msg.plan.FormatErrors() {
+			m.log += "Warning: " + warning + "\n"
+		}
+		m.logView.SetContent(m.log)
+		return m.nextTask()
+
+	case stepDoneMsg:
+		status := taskDone
+		if msg.err != nil {
+			status = taskFailed
+		}
+		m.tasks = setTask(m.tasks, msg.index, status, msg.err)
+		return m.nextTask()
+	}
+
+	return m, nil
+}
+
+// nextTask starts the next pending step, or finishes after the last one, a
+// failure or a cancellation
+func (m Model) nextTask() (tea.Model, tea.Cmd) {
+	next := -1
+	for i, t := range m.tasks {
+		if t.status == taskFailed {
+			next = -1
+			break
+		}
+		if t.status == taskPending && next < 0 {
+			next = i
+		}
+	}
+
+	if next < 0 || m.cancelled {
+		// Whatever did not run is skipped
+		for i, t := range m.tasks {
+			if t.status == taskPending {
+				m.tasks = setTask(m.tasks, i, taskSkipped, nil)
+			}
+		}
+		m.cancel()
+		if m.cancelled {
+			m.quitting = true
+			return m, tea.Quit
+		}
+		m.currentStage = StageSuccess
+		return m, nil
+	}
+
+	config, err := m.Config()
+	if err != nil {
+		m.tasks = setTask(m.tasks, next, taskFailed, err)
+		return m.nextTask()
+	}
+	m.tasks = setTask(m.tasks, next, taskRunning, nil)
+	return m, runTaskCmd(m.ctx, next, m.tasks[next], config.TargetDir, m.logCh)
+}
+
+// setTask returns a copy of tasks with the status of task i changed, so
+// earlier models stay unchanged
+func setTask(tasks []task, i int, status taskStatus, err error) []task {
+	updated := make([]task, len(tasks))
+	copy(updated, tasks)
+	updated[i].status = status
+	updated[i].err = err
+	return updated
+}
+
+// logWidth returns the width of the log viewport
+func (m Model) logWidth() int {
+	if m.width > 0 {
+		return m.width
+	}
+	return logWidth
+}
+
+// logHeight returns the height of the log viewport, which gets the
+// terminal rows left below the task list
+func (m Model) logHeight() int {
+	if m.height > 0 {
+		return max(m.height-len(m.tasks)-8, 3)
+	}
+	return logHeight
+}
+
+// resize fits the log viewport to the terminal below the task list
+func (m Model) resize(msg tea.WindowSizeMsg) Model {
+	m.width, m.height = msg.Width, msg.Height
+	m.logView.Width = m.logWidth()
+	m.logView.Height = m.logHeight()
+	return m
+}
+
+// renderProgress renders the tasks, the log of the running step and the key help
+func (m Model) renderProgress() string {
+	s := fmt.Sprintf("Creating %s in %s\n\n", m.projectName, m.getTargetDir())
+
+	for _, t := range m.tasks {
+		switch t.status {
+		case taskRunning:
+			if m.cancelled {
+				s += fmt.Sprintf("  %s %s \x1b[2m(cancelling)\x1b[0m\n", m.spinner.View(), t.label)
+			} else {
+				s += fmt.Sprintf("  %s %s\n", m.spinner.View(), t.label)
+			}
+		case taskDone:
+			s += fmt.Sprintf("  \x1b[32m✓\x1b[0m %s\n", t.label)
+		case taskFailed:
+			s += fmt.Sprintf("  \x1b[31m✗\x1b[0m %s\n", t.label)
+		case taskSkipped:
+			s += fmt.Sprintf("  \x1b[2m- %s (skipped)\x1b[0m\n", t.label)
+		default:
+			s += fmt.Sprintf("  \x1b[2m· %s\x1b[0m\n", t.label)
+		}
+	}
+
+	if m.log != "" {
+		s += "\n" + strings.Repeat("─", m.logView.Width) + "\n"
+		s += m.logView.View() + "\n"
+		s += strings.Repeat("─", m.logView.Width) + "\n"
+	}
+
+	return s + "\n(ctrl+c to cancel, arrow keys to scroll the log)"
+}
+
+// renderTasks renders the outcome of the post-generation steps and the git
+// repository for the success screen
+func (m Model) renderTasks() string {
+	s := ""
+	for _, t := range m.tasks {
+		if t.run == nil {
+			continue
+		}
+		switch t.status {
+		case taskDone:
+			s += fmt.Sprintf("\x1b[32m✓\x1b[0m %s\n", t.label)
+		case taskFailed:
+			s += fmt.Sprintf("\x1b[31m✗ %s failed:\x1b[0m\n%v\n", t.label, t.err)
+		case taskSkipped:
+			s += fmt.Sprintf("\x1b[2m- %s (skipped)\x1b[0m\n", t.label)
+		}
+	}
+	if s != "" {
+		s += "\n"
+	}
+	return s
+}
+
+// PostStepError returns the error of the post-generation step, verification
+// or git initialization that failed, if any
+func (m Model) PostStepError() error {
+	for _, t := range m.tasks {
+		if t.run != nil && t.status == taskFailed {
+			return fmt.Errorf("%s: %w", t.name, t.err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package prompts
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+// progressTemplate writes a template whose post-generation steps run the given commands
+func progressTemplate(t *testing.T, steps ...string) string {
+	t.Helper()
+	t.Setenv(generator.TemplatePathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	manifest := "name: Worker\nappType: worker\npackage: stdlib\npostGenerate:\n"
+	for i, run := range steps {
+		manifest += "  - name: step" + string(rune('a'+i)) + "\n    run: " + run + "\n    default: true\n"
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, generator.ManifestFile), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("worker\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestProgress(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	templateDir := progressTemplate(t, `sh -c "echo from the step"`, `sh -c "echo broken >&2; exit 1"`, "sh -c true")
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	model := NewModelWithOptions(Options{ProjectName: "my-worker", ModuleName: "example.com/my-worker", AppType: "worker", Package: "stdlib", Template: templateDir, TargetDir: targetDir})
+
+	// Steps of a template from disk are suggested but not selected
+	if view := model.View(); !strings.Contains(view, "[ ] sh -c true") || !strings.Contains(view, "suggested by the template") {
+		t.Errorf("Post-generation steps should be suggested only:\n%s", view)
+	}
+	model = pressKeys(t, model, typeText(" "), typeText("j"), typeText(" "), typeText("j"), typeText(" "), enter, typeText("y"))
+	updatedModel, _ := model.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	model = updatedModel.(Model)
+
+	// Confirming the summary moves to the progress stage at once
+	updatedModel, cmd := model.Update(enter)
+	model = updatedModel.(Model)
+	if model.currentStage != StageProgress || cmd == nil {
+		t.Fatalf("Expected stage %d with a command, got %d", StageProgress, model.currentStage)
+	}
+
+	// The log fills the terminal below the five tasks
+	if model.logView.Width != 100 || model.logView.Height != 40-5-8 {
+		t.Errorf("Expected a 100x27 log, got %dx%d", model.logView.Width, model.logView.Height)
+	}
+	if view := model.View(); !strings.Contains(view, "Generate project files") || !strings.Contains(view, `sh -c "echo from the step"`) || !strings.Contains(view, "Initialize git repository") {
+		t.Errorf("Progress view should list the tasks:\n%s", view)
+	}
+
+	model = runCmds(t, model, cmd)
+	if model.currentStage != StageSuccess || !model.GenerationSuccess() {
+		t.Fatalf("Expected stage %d, got %d (%v)", StageSuccess, model.currentStage, model.generationError)
+	}
+	if !strings.Contains(model.log, "from the step") {
+		t.Errorf("Step output should be in the log:\n%s", model.log)
+	}
+
+	// The failing step stops the rest, including the git repository
+	statuses := []taskStatus{taskDone, taskDone, taskFailed, taskSkipped, taskSkipped}
+	for i, status := range statuses {
+		if model.tasks[i].status != status {
+			t.Errorf("Task %d (%s): status %d, want %d", i, model.tasks[i].label, model.tasks[i].status, status)
+		}
+	}
+	if err := model.PostStepError(); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected the step error with its output, got %v", err)
+	}
+	if view := model.View(); !strings.Contains(view, "failed") || !strings.Contains(view, "(skipped)") {
+		t.Errorf("Success screen should report the steps:\n%s", view)
+	}
+}
+
+func TestProgressCancel(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+	templateDir := progressTemplate(t, "sleep 30")
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	model := NewModelWithOptions(Options{ProjectName: "my-worker", ModuleName: "example.com/my-worker", AppType: "worker", Package: "stdlib", Template: templateDir, TargetDir: targetDir, PostSteps: []string{"stepa"}, Git: &generator.GitOptions{}})
+
+	updatedModel, _ := model.Update(enter)
+	model = updatedModel.(Model)
+
+	// Generate, then start the step in the background
+	updatedModel, stepCmd := model.Update(generatedMsg{plan: &generator.Plan{}})
+	model = updatedModel.(Model)
+	if model.tasks[1].status != taskRunning || stepCmd == nil {
+		t.Fatalf("Expected the step to run, got status %d", model.tasks[1].status)
+	}
+	done := make(chan tea.Msg)
+	go func() { done <- stepCmd() }()
+
+	// ctrl+c kills it
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	model = pressKeys(t, model, tea.KeyMsg{Type: tea.KeyCtrlC})
+	if !strings.Contains(model.View(), "(cancelling)") {
+		t.Errorf("Progress view should show the cancellation:\n%s", model.View())
+	}
+	var msg tea.Msg
+	select {
+	case msg = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("The step was not killed")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Killing the step took %s", elapsed)
+	}
+
+	updatedModel, cmd := model.Update(msg)
+	model = updatedModel.(Model)
+	if cmd == nil || !model.Cancelled() || !model.GenerationSuccess() {
+		t.Errorf("Expected to quit after cancelling, cancelled=%v", model.Cancelled())
+	}
+}
+
+func TestProgressVerify(t *testing.T) {
+	templateDir := progressTemplate(t)
+	if err := os.WriteFile(filepath.Join(templateDir, "main.go"), []byte("package main\n\nfunc main() {\n\tx :=\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	targetDir := filepath.Join(t.TempDir(), "svc")
+	model := NewModelWithOptions(Options{ProjectName: "my-worker", ModuleName: "example.com/my-worker", AppType: "worker", Package: "stdlib", Template: templateDir, TargetDir: targetDir, PostSteps: []string{}, Git: &generator.GitOptions{}, Verify: generator.VerifySyntax})
+
+	updatedModel, cmd := model.Update(enter)
+	model = runCmds(t, updatedModel.(Model), cmd)
+	if model.currentStage != StageSuccess {
+		t.Fatalf("Expected stage %d, got %d (%v)", StageSuccess, model.currentStage, model.generationError)
+	}
+
+	// The problems are listed by file and line
+	if err := model.PostStepError(); err == nil || !strings.Contains(err.Error(), "main.go:5:1: expected operand") {
+		t.Errorf("Expected the verification error, got %v", err)
+	}
+	if view := model.View(); !strings.Contains(view, "main.go:5:1: expected operand") {
+		t.Errorf("Success screen should list the problems:\n%s", view)
+	}
+}
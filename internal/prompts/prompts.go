@@ -1,10 +1,12 @@
 package prompts
 
 import (
+	"context"
 	"fmt"
-	"regexp"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/manuelbamise/go-ten/internal/generator"
 )
@@ -13,11 +15,11 @@ import (
 type Stage int
 
 const (
-	Stage1ProjectName Stage = iota
-	Stage2AppType
-	Stage3Package
-	Stage4Summary
-	Stage5Success
+	StageQuestions Stage = iota // asks the steps in order
+	StageSummary
+	StageConflicts // asks what to do with each existing file before generating
+	StageProgress  // generates the project and runs the post-generation steps
+	StageSuccess
 )
 
 // Model represents the state of our multi-step selection UI
@@ -25,46 +27,332 @@ type Model struct {
 	// Stage management
 	currentStage Stage
 
-	// Stage 1: Project Name Input
-	projectName string
-	inputValue  string
-	inputCursor int
+	// Questions asked in order; later steps depend on earlier answers
+	steps     []Step
+	stepIndex int
+	stepError error
 
-	// Stage 2: Application Type Selection
-	appTypes        []string
-	appTypeCursor   int
-	selectedAppType string
+	// Answers given up front, whose steps are skipped
+	preset     map[string]bool
+	presetVars map[string]bool
 
-	// Stage 3: Package Selection
-	packages        []string
-	packageCursor   int
+	// Templates discovered from their manifests, from templateDir, the user
+	// template directories and the embedded ones
+	templates   []generator.Template
+	templateDir string
+
+	// Built-in answers
+	projectName     string
+	selectedAppType string // display name of the app type
 	selectedPackage string
 
-	// Stage 4: Summary
-	quitting bool
+	// Summary
+	targetDir  string
+	moduleName string         // Go module path, defaults from the project name
+	vars       map[string]any // template variables, passed to templates as .Vars
+	postSteps  []string       // post-generation steps to run; nil for none
+	git        generator.GitOptions
+	verify     generator.VerifyLevel // checks of the generated code
+	quitting   bool
+
+	// Conflict resolution: existing files and the user's choice for each
+	onConflict    generator.ConflictPolicy
+	conflicts     []string
+	conflictIndex int
+	resolutions   map[string]generator.ConflictPolicy
+
+	// Dry run preview shown on the summary screen
+	showPlan bool
+	planTree string
+	planErr  error
 
 	// Generation state
 	generationError   error
 	generationSuccess bool
+
+	// Progress of generating the files and running the post-generation steps
+	tasks     []task
+	spinner   spinner.Model
+	logView   viewport.Model
+	log       string
+	logCh     chan string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	cancelled bool // the user cancelled while the project was generated
+	width     int  // terminal width, once known
+	height    int  // terminal height, once known
 }
 
 // NewModel creates a new model with default values
 func NewModel() Model {
-	appTypes := []string{
-		"Web API",
+	return NewModelWithOptions(Options{})
+}
+
+// Options holds values that were supplied up front, e.g. from command line flags.
+// Empty fields are asked for interactively.
+type Options struct {
+	ProjectName string
+	AppType     string // template app type, e.g. "web-api"
+	Package     string
+	Template    string // extra template directory, searched first
+	TargetDir   string
+	ModuleName  string
+	Vars        map[string]any
+
+	// OnConflict applies to every existing file; when empty the user is asked per file
+	OnConflict generator.ConflictPolicy
+
+	// PostSteps names the post-generation steps to run; when nil the user chooses
+	PostSteps []string
+
+	// Git configures the git repository; when nil the user is asked whether to create one
+	Git *generator.GitOptions
+
+	// Verify checks the generated code after the post-generation steps
+	Verify generator.VerifyLevel
+}
+
+// NewModelWithOptions creates a model with the answers in opts filled in,
+// asking only for what is still missing
+func NewModelWithOptions(opts Options) Model {
+	m := Model{
+		currentStage: StageQuestions,
+		preset:       map[string]bool{},
+		presetVars:   map[string]bool{},
+		targetDir:    opts.TargetDir,
+		templateDir:  opts.Template,
+		moduleName:   opts.ModuleName,
+		vars:         map[string]any{},
+		onConflict:   opts.OnConflict,
+		postSteps:    opts.PostSteps,
+		verify:       opts.Verify,
+	}
+
+	// Offer the templates declared by the template manifests
+	templates, err := generator.TemplatesIn(opts.Template)
+	if err != nil {
+		m.generationError = err
+	}
+	m.templates = templates
+
+	if opts.ProjectName != "" {
+		m.projectName = opts.ProjectName
+		m.preset[keyName] = true
+	}
+	if opts.ModuleName != "" {
+		m.preset[keyModule] = true
+	}
+	if opts.AppType != "" {
+		m.selectedAppType = opts.AppType
+		if label := m.appTypeLabel(opts.AppType); label != "" {
+			m.selectedAppType = label
+		}
+		m.preset[keyAppType] = true
+	}
+	if opts.Package != "" {
+		m.selectedPackage = opts.Package
+		m.preset[keyPackage] = true
+	}
+	for name, value := range opts.Vars {
+		m.vars[name] = value
+		m.presetVars[name] = true
+	}
+	if opts.PostSteps != nil {
+		m.preset[keyPostSteps] = true
+	}
+	if opts.Git != nil {
+		m.git = *opts.Git
+		m.preset[keyGit] = true
+	}
+
+	m.steps = m.pendingSteps("")
+	if len(m.steps) == 0 {
+		m.currentStage = StageSummary
+	}
+
+	return m
+}
+
+// pendingSteps returns the steps that follow the built-in step with key after
+// ("" for all of them), skipping answers given up front. Template variables
+// are included once the template is known.
+func (m Model) pendingSteps(after string) []Step {
+	var steps []Step
+
+	started := after == ""
+	for _, key := range []string{keyName, keyModule, keyAppType, keyPackage} {
+		if started && !m.preset[key] {
+			steps = append(steps, m.builtinStep(key))
+		}
+		if key == after {
+			started = true
+		}
+	}
+
+	// Ask for the template variables once the template is chosen
+	if len(steps) == 0 {
+		if tmpl := m.selectedTemplate(); tmpl != nil {
+			for _, v := range tmpl.Variables {
+				if !m.presetVars[v.Name] {
+					steps = append(steps, variableStep(v))
+				}
+			}
+			if !m.preset[keyPostSteps] {
+				steps = append(steps, postStepsStep(tmpl))
+			}
+			if !m.preset[keyGit] {
+				info := stepInfo{
+					key:         keyGit,
+					title:       "Initialize a git repository?",
+					description: "Adds a .gitignore and commits the generated files; --git-branch and --git-author set the branch and author",
+				}
+				steps = append(steps, newConfirmStep(info, m.git.Init))
+			}
+		}
+	}
+
+	return steps
+}
+
+// builtinStep creates the step for a built-in question
+func (m Model) builtinStep(key string) Step {
+	switch key {
+	case keyName:
+		info := stepInfo{
+			key:   keyName,
+			title: "Enter your project name (or '.' for current directory):",
+			validate: func(value any) (any, error) {
+				return value, m.validateProjectName(value.(string))
+			},
+		}
+		return newTextStep(info, m.projectName)
+
+	case keyModule:
+		info := stepInfo{
+			key:         keyModule,
+			title:       "Enter the Go module path:",
+			description: "Used in go.mod and imports, e.g. github.com/ourorg/my-api",
+			validate: func(value any) (any, error) {
+				return value, generator.ValidateModulePath(value.(string))
+			},
+		}
+		return newTextStep(info, m.defaultModulePath())
+
+	case keyAppType:
+		var options []option
+		for _, tmpl := range m.templates {
+			if !hasOption(options, tmpl.AppType) {
+				options = append(options, option{label: tmpl.Name, value: tmpl.AppType, description: sourceNote(tmpl)})
+			}
+		}
+		info := stepInfo{key: keyAppType, title: "Select application type:"}
+		return newSelectStep(info, options, m.appTypeID(m.selectedAppType))
+
+	default:
+		// Packages of the chosen app type, or of the first one
+		appType := m.appTypeID(m.selectedAppType)
+		if appType == "" && len(m.templates) > 0 {
+			appType = m.templates[0].AppType
+		}
+		var options []option
+		for _, tmpl := range m.templates {
+			if tmpl.AppType == appType {
+				description := tmpl.Description
+				if note := sourceNote(tmpl); note != "" {
+					description += " " + note
+				}
+				options = append(options, option{label: tmpl.Package, value: tmpl.Package, description: description})
+			}
+		}
+		info := stepInfo{key: keyPackage, title: "Select package:"}
+		return newSelectStep(info, options, m.selectedPackage)
+	}
+}
+
+// postStepsStep asks which commands to run after generation. The defaults
+// of the built-in templates start out selected; those of other templates,
+// which run commands go-ten does not ship, only get marked as suggested.
+func postStepsStep(tmpl *generator.Template) Step {
+	trusted := tmpl.Source == generator.SourceEmbedded
+	var options []option
+	var defaults []string
+	for _, step := range tmpl.PostSteps() {
+		description := step.Description
+		switch {
+		case step.Default && trusted:
+			defaults = append(defaults, step.Name)
+		case step.Default:
+			description = strings.TrimSpace(description + " (suggested by the template)")
+		}
+		options = append(options, option{label: step.Run, value: step.Name, description: description})
+	}
+	info := stepInfo{
+		key:         keyPostSteps,
+		title:       "Run after generating the project:",
+		description: "Commands run in the new project, in this order",
+	}
+	return newMultiSelectStep(info, options, defaults)
+}
+
+// sourceNote tells where a template on disk was found; embedded templates get none
+func sourceNote(tmpl generator.Template) string {
+	if tmpl.Source == generator.SourceEmbedded {
+		return ""
+	}
+	return fmt.Sprintf("(from %s)", tmpl.Source)
+}
+
+// defaultModulePath returns the module path suggested for the project name
+func (m Model) defaultModulePath() string {
+	if m.moduleName != "" {
+		return m.moduleName
+	}
+	config, err := generator.NewProjectConfig(m.projectName, "", "", m.targetDir)
+	if err != nil {
+		return ""
 	}
+	return config.ModuleName
+}
 
-	packages := []string{
-		"stdlib",
+// hasOption reports whether options contains value
+func hasOption(options []option, value string) bool {
+	for _, opt := range options {
+		if opt.value == value {
+			return true
+		}
 	}
+	return false
+}
 
-	return Model{
-		currentStage:  Stage1ProjectName,
-		appTypes:      appTypes,
-		appTypeCursor: 0,
-		packages:      packages,
-		packageCursor: 0,
+// appTypeID returns the template app type for a displayed application type
+func (m Model) appTypeID(label string) string {
+	for _, tmpl := range m.templates {
+		if tmpl.Name == label {
+			return tmpl.AppType
+		}
 	}
+	return label
+}
+
+// appTypeLabel returns the displayed name of a template app type
+func (m Model) appTypeLabel(id string) string {
+	for _, tmpl := range m.templates {
+		if tmpl.AppType == id {
+			return tmpl.Name
+		}
+	}
+	return ""
+}
+
+// selectedTemplate returns the chosen template, or nil if it is not known yet
+func (m Model) selectedTemplate() *generator.Template {
+	appType := m.appTypeID(m.selectedAppType)
+	for i := range m.templates {
+		if m.templates[i].AppType == appType && m.templates[i].Package == m.selectedPackage {
+			return &m.templates[i]
+		}
+	}
+	return nil
 }
 
 // Init initializes the bubbletea program
@@ -74,145 +362,233 @@ func (m Model) Init() tea.Cmd {
 
 // Update handles incoming messages and updates the model state
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		return m.resize(size), nil
+	}
+
+	// The progress stage handles its own messages, including ctrl+c
+	if m.currentStage == StageProgress {
+		return m.updateProgress(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
-		// Quit keys (available in all stages except success)
+		// Quit keys (available in all stages except success).
+		// "q" is ordinary input while typing text.
 		case "q", "ctrl+c":
-			if m.currentStage != Stage5Success {
+			if m.currentStage != StageSuccess && (msg.String() == "ctrl+c" || !m.typingText()) {
 				m.quitting = true
 				return m, tea.Quit
 			}
+		}
 
 		// Stage-specific key handling
-		default:
-			switch m.currentStage {
-			case Stage1ProjectName:
-				return m.updateStage1(msg)
-			case Stage2AppType:
-				return m.updateStage2(msg)
-			case Stage3Package:
-				return m.updateStage3(msg)
-			case Stage4Summary:
-				return m.updateStage4(msg)
-			case Stage5Success:
-				return m.updateStage5(msg)
-			}
+		switch m.currentStage {
+		case StageQuestions:
+			return m.updateQuestions(msg)
+		case StageSummary:
+			return m.updateSummary(msg)
+		case StageSuccess:
+			return m.updateSuccess(msg)
+		case StageConflicts:
+			return m.updateConflicts(msg)
 		}
 	}
 
 	return m, nil
 }
 
-// updateStage1 handles key input for project name input
-func (m Model) updateStage1(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.Type {
-	case tea.KeyEnter:
-		// Validate input
-		if err := m.validateProjectName(m.inputValue); err != nil {
-			m.generationError = err
-			return m, nil
-		}
+// typingText reports whether the current step is a text input
+func (m Model) typingText() bool {
+	if m.currentStage != StageQuestions || m.stepIndex >= len(m.steps) {
+		return false
+	}
+	_, ok := m.steps[m.stepIndex].(textStep)
+	return ok
+}
 
-		// Set project name and move to next stage
-		m.projectName = m.inputValue
-		m.currentStage = Stage2AppType
+// updateQuestions passes key input to the current step and records its answer
+func (m Model) updateQuestions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.stepIndex >= len(m.steps) {
+		m.currentStage = StageSummary
 		return m, nil
+	}
 
-	case tea.KeyBackspace:
-		if len(m.inputValue) > 0 {
-			// Remove character at cursor position
-			if m.inputCursor > 0 {
-				m.inputValue = m.inputValue[:m.inputCursor-1] + m.inputValue[m.inputCursor:]
-				m.inputCursor--
-			}
-		}
+	step, submitted := m.steps[m.stepIndex].Update(msg)
 
-	case tea.KeyLeft:
-		if m.inputCursor > 0 {
-			m.inputCursor--
-		}
+	// Copy the steps so earlier models stay unchanged
+	steps := make([]Step, len(m.steps))
+	copy(steps, m.steps)
+	steps[m.stepIndex] = step
+	m.steps = steps
+	m.stepError = nil
 
-	case tea.KeyRight:
-		if m.inputCursor < len(m.inputValue) {
-			m.inputCursor++
-		}
+	if !submitted {
+		return m, nil
+	}
+
+	// Validate input
+	value, err := step.Submit()
+	if err != nil {
+		m.stepError = err
+		return m, nil
+	}
+	m.setAnswer(step.Key(), value)
 
-	case tea.KeyRunes:
-		// Add character at cursor position
-		m.inputValue = m.inputValue[:m.inputCursor] + string(msg.Runes) + m.inputValue[m.inputCursor:]
-		m.inputCursor += len(msg.Runes)
+	// Later steps depend on the project name and the chosen template
+	if key := step.Key(); key == keyName || key == keyAppType || key == keyPackage {
+		m.steps = append(m.steps[:m.stepIndex+1:m.stepIndex+1], m.pendingSteps(key)...)
+	}
+
+	// Move to the next step, or to the summary after the last one
+	m.stepIndex++
+	if m.stepIndex >= len(m.steps) {
+		m.currentStage = StageSummary
 	}
 
 	return m, nil
 }
 
-// updateStage2 handles key input for application type selection
-func (m Model) updateStage2(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	// Navigation keys
-	case "up", "k":
-		if m.appTypeCursor > 0 {
-			m.appTypeCursor--
+// setAnswer stores the answer of the step with key
+func (m *Model) setAnswer(key string, value any) {
+	switch key {
+	case keyName:
+		m.projectName = value.(string)
+	case keyModule:
+		m.moduleName = value.(string)
+	case keyAppType:
+		m.selectedAppType = m.appTypeLabel(value.(string))
+	case keyPackage:
+		m.selectedPackage = value.(string)
+	case keyPostSteps:
+		m.postSteps = value.([]string)
+	case keyGit:
+		m.git.Init = value.(bool)
+	default:
+		vars := make(map[string]any, len(m.vars)+1)
+		for name, v := range m.vars {
+			vars[name] = v
 		}
-
-	case "down", "j":
-		if m.appTypeCursor < len(m.appTypes)-1 {
-			m.appTypeCursor++
+		vars[key] = value
+		if value == nil {
+			// Left unset, so the template default applies
+			delete(vars, key)
 		}
-
-	// Selection key - advance to stage 3
-	case "enter":
-		m.selectedAppType = m.appTypes[m.appTypeCursor]
-		m.currentStage = Stage3Package
+		m.vars = vars
 	}
-
-	return m, nil
 }
 
-// updateStage3 handles key input for package selection
-func (m Model) updateStage3(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+// updateSummary handles key input for summary stage
+func (m Model) updateSummary(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
-	// Navigation keys
-	case "up", "k":
-		if m.packageCursor > 0 {
-			m.packageCursor--
+	// Confirm and create project
+	case "enter":
+		// Ask about existing files first unless a policy was given up front
+		if m.onConflict == "" {
+			config, err := m.Config()
+			if err != nil {
+				m.generationError = err
+				return m, nil
+			}
+			conflicts, err := generator.DetectConflicts(config)
+			if err != nil {
+				m.generationError = err
+				return m, nil
+			}
+			if len(conflicts) > 0 {
+				m.conflicts = conflicts
+				m.conflictIndex = 0
+				m.resolutions = make(map[string]generator.ConflictPolicy, len(conflicts))
+				m.currentStage = StageConflicts
+				return m, nil
+			}
 		}
 
-	case "down", "j":
-		if m.packageCursor < len(m.packages)-1 {
-			m.packageCursor++
-		}
+		return m.finishGeneration()
 
-	// Selection key - advance to stage 4
-	case "enter":
-		m.selectedPackage = m.packages[m.packageCursor]
-		m.currentStage = Stage4Summary
+	// Toggle the preview of the files that will be generated
+	case "p":
+		m.showPlan = !m.showPlan
+		if m.showPlan {
+			m.planTree, m.planErr = m.renderPlan()
+		}
 	}
 
 	return m, nil
 }
 
-// updateStage4 handles key input for summary stage
-func (m Model) updateStage4(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+// renderPlan runs a dry run of the current configuration and renders it as a tree
+func (m Model) renderPlan() (string, error) {
+	config, err := m.Config()
+	if err != nil {
+		return "", err
+	}
+
+	plan, err := generator.DryRun(config)
+	if err != nil {
+		return "", err
+	}
+
+	return plan.Tree(), nil
+}
+
+// updateConflicts handles key input for the per-file conflict prompt
+func (m Model) updateConflicts(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var policy generator.ConflictPolicy
+	applyToAll := false
+
 	switch msg.String() {
-	// Confirm and create project
-	case "enter":
-		// Generate the project
-		if err := m.generateProject(); err != nil {
-			m.generationError = err
-			return m, nil
+	case "o", "O":
+		policy = generator.ConflictOverwrite
+	case "s", "S":
+		policy = generator.ConflictSkip
+	case "n", "N":
+		policy = generator.ConflictNew
+	case "a", "esc":
+		// Go back to the summary without writing anything
+		m.generationError = &generator.ConflictError{Paths: m.conflicts[m.conflictIndex:]}
+		m.currentStage = StageSummary
+		return m, nil
+	default:
+		return m, nil
+	}
+
+	// Upper case keys apply the choice to all remaining files
+	if len(msg.Runes) == 1 && msg.Runes[0] >= 'A' && msg.Runes[0] <= 'Z' {
+		applyToAll = true
+	}
+
+	for m.conflictIndex < len(m.conflicts) {
+		m.resolutions[m.conflicts[m.conflictIndex]] = policy
+		m.conflictIndex++
+		if !applyToAll {
+			break
 		}
+	}
 
-		m.generationSuccess = true
-		m.currentStage = Stage5Success
+	if m.conflictIndex < len(m.conflicts) {
+		return m, nil
 	}
 
-	return m, nil
+	// Every conflict is resolved
+	m.currentStage = StageSummary
+	return m.finishGeneration()
 }
 
-// updateStage5 handles key input for success stage
-func (m Model) updateStage5(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+// finishGeneration starts generating the project in the background and
+// moves to the progress stage
+func (m Model) finishGeneration() (tea.Model, tea.Cmd) {
+	config, err := m.Config()
+	if err != nil {
+		m.generationError = err
+		return m, nil
+	}
+	return m.startProgress(config)
+}
+
+// updateSuccess handles key input for success stage
+func (m Model) updateSuccess(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Any key exits
 	m.quitting = true
 	return m, tea.Quit
@@ -226,108 +602,52 @@ func (m Model) View() string {
 
 	// Render based on current stage
 	switch m.currentStage {
-	case Stage1ProjectName:
-		return m.renderStage1()
-	case Stage2AppType:
-		return m.renderStage2()
-	case Stage3Package:
-		return m.renderStage3()
-	case Stage4Summary:
-		return m.renderStage4()
-	case Stage5Success:
-		return m.renderStage5()
+	case StageQuestions:
+		return m.renderQuestion()
+	case StageSummary:
+		return m.renderSummary()
+	case StageSuccess:
+		return m.renderSuccess()
+	case StageConflicts:
+		return m.renderConflicts()
+	case StageProgress:
+		return m.renderProgress()
 	default:
 		return "Error: Unknown stage"
 	}
 }
 
-// renderStage1 renders the project name input screen
-func (m Model) renderStage1() string {
-	s := "Enter your project name (or '.' for current directory):\n\n"
-
-	// Show input field with cursor
-	s += "> "
-
-	// Display input with cursor indicator
-	for i, char := range m.inputValue {
-		if i == m.inputCursor {
-			s += fmt.Sprintf("%c|", char)
-		} else {
-			s += string(char)
-		}
+// renderQuestion renders the current step
+func (m Model) renderQuestion() string {
+	if m.stepIndex >= len(m.steps) {
+		return ""
 	}
 
-	// Show cursor at end if at the end of input
-	if m.inputCursor == len(m.inputValue) {
-		s += "|"
-	}
+	s := m.steps[m.stepIndex].View()
 
 	// Show error if validation failed
-	if m.generationError != nil {
-		s += fmt.Sprintf("\n\nError: %v", m.generationError)
-		m.generationError = nil // Clear error after displaying
-	}
-
-	s += "\n\n(Enter to submit, q to quit)"
-	return s
-}
-
-// renderStage2 renders the application type selection screen
-func (m Model) renderStage2() string {
-	s := "Select application type:\n\n"
-
-	// Render the list of application types
-	for i, appType := range m.appTypes {
-		// Cursor indicator
-		cursor := " "
-		if m.appTypeCursor == i {
-			cursor = ">"
-		}
-
-		// Highlight the currently selected option
-		if m.appTypeCursor == i {
-			s += fmt.Sprintf("%s \x1b[1m%s\x1b[0m\n", cursor, appType)
-		} else {
-			s += fmt.Sprintf("%s %s\n", cursor, appType)
-		}
+	if m.stepError != nil {
+		s += fmt.Sprintf("\n\n\x1b[31mError: %v\x1b[0m", m.stepError)
+	} else if m.generationError != nil {
+		s += fmt.Sprintf("\n\n\x1b[31mError: %v\x1b[0m", m.generationError)
 	}
 
-	s += "\n(Use arrow keys to navigate, press Enter to continue, q to quit)"
 	return s
 }
 
-// renderStage3 renders the package selection screen
-func (m Model) renderStage3() string {
-	s := "Select package:\n\n"
-
-	// Render the list of packages
-	for i, pkg := range m.packages {
-		// Cursor indicator
-		cursor := " "
-		if m.packageCursor == i {
-			cursor = ">"
-		}
-
-		// Highlight the currently selected option
-		if m.packageCursor == i {
-			s += fmt.Sprintf("%s \x1b[1m%s\x1b[0m\n", cursor, pkg)
-		} else {
-			s += fmt.Sprintf("%s %s\n", cursor, pkg)
-		}
-	}
-
-	s += "\n(Use arrow keys to navigate, press Enter to continue, q to quit)"
-	return s
-}
-
-// renderStage4 renders the summary screen
-func (m Model) renderStage4() string {
+// renderSummary renders the summary screen
+func (m Model) renderSummary() string {
 	s := "Project Configuration Summary\n\n"
 
 	// Display project name
 	targetDir := m.getTargetDir()
 	s += fmt.Sprintf("Name: \x1b[1m%s\x1b[0m\n", m.projectName)
 
+	// Display the Go module path
+	if config, err := m.Config(); err == nil {
+		s += fmt.Sprintf("Module: \x1b[1m%s\x1b[0m\n", config.ModuleName)
+	}
+
 	// Display selected application type
 	s += fmt.Sprintf("Type: \x1b[1m%s\x1b[0m\n", m.selectedAppType)
 
@@ -337,20 +657,56 @@ func (m Model) renderStage4() string {
 	// Display target location
 	s += fmt.Sprintf("Location: \x1b[1m%s\x1b[0m\n", targetDir)
 
+	// Display template variables in manifest order
+	if tmpl := m.selectedTemplate(); tmpl != nil {
+		for _, v := range tmpl.Variables {
+			if value, ok := m.vars[v.Name]; ok {
+				s += fmt.Sprintf("%s: \x1b[1m%v\x1b[0m\n", v.Name, value)
+			}
+		}
+	}
+
+	// Display whether a git repository is created
+	if m.git.Init {
+		s += "Git: \x1b[1myes\x1b[0m\n"
+	}
+
+	// Show the dry run preview
+	if m.showPlan {
+		if m.planErr != nil {
+			s += fmt.Sprintf("\n\x1b[31mCannot preview files: %v\x1b[0m\n", m.planErr)
+		} else {
+			s += "\nFiles to be generated:\n\n" + m.planTree
+		}
+	}
+
 	// Show error if generation failed
 	if m.generationError != nil {
 		s += fmt.Sprintf("\n\x1b[31mError: %v\x1b[0m\n", m.generationError)
-		s += "\nPress Enter to retry or 'q' to quit"
+		s += "\nPress Enter to retry, 'p' to toggle the file preview or 'q' to quit"
 	} else {
-		s += "\nPress Enter to generate or 'q' to quit"
+		s += "\nPress Enter to generate, 'p' to toggle the file preview or 'q' to quit"
 	}
 
 	return s
 }
 
-// renderStage5 renders the success screen
-func (m Model) renderStage5() string {
+// renderConflicts renders the prompt for the current conflicting file
+func (m Model) renderConflicts() string {
+	s := fmt.Sprintf("File already exists (%d of %d):\n\n", m.conflictIndex+1, len(m.conflicts))
+	s += fmt.Sprintf("  \x1b[1m%s\x1b[0m\n\n", m.conflicts[m.conflictIndex])
+	s += "[o] overwrite  [s] skip  [n] write as .new  [a] abort\n"
+	s += "\n(Use upper case O, S or N to apply to all remaining files, q to quit)"
+	return s
+}
+
+// renderSuccess renders the success screen
+func (m Model) renderSuccess() string {
 	s := "\x1b[32m✓ Project created successfully!\x1b[0m\n\n"
+
+	// Report the post-generation steps
+	s += m.renderTasks()
+
 	s += "Next steps:\n"
 
 	targetDir := m.getTargetDir()
@@ -358,76 +714,71 @@ func (m Model) renderStage5() string {
 		s += fmt.Sprintf("cd %s\n", targetDir)
 	}
 
-	s += "go mod tidy\n"
-	s += "go run ./cmd/api\n\n"
-	s += fmt.Sprintf("Your Web API is ready at: %s\n", targetDir)
+	// The template knows how its projects are built and run
+	for _, step := range m.nextSteps() {
+		s += step + "\n"
+	}
+
+	s += fmt.Sprintf("\nYour %s is ready at: %s\n", m.selectedAppType, targetDir)
 	s += "\nPress any key to exit"
 
 	return s
 }
 
-// validateProjectName validates the project name input
-func (m Model) validateProjectName(name string) error {
-	if strings.TrimSpace(name) == "" {
-		return fmt.Errorf("project name cannot be empty")
+// nextSteps returns the commands the template suggests after generation
+func (m Model) nextSteps() []string {
+	tmpl := m.selectedTemplate()
+	if tmpl == nil {
+		return nil
 	}
 
-	// Allow "." as special case for current directory
-	if name == "." {
+	config, err := m.Config()
+	if err != nil {
 		return nil
 	}
 
-	// Validate project name format (alphanumeric, hyphens, underscores)
-	validName := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
-	if !validName.MatchString(name) {
-		return fmt.Errorf("project name must contain only letters, numbers, hyphens, and underscores")
+	steps, err := tmpl.RenderNextSteps(config)
+	if err != nil {
+		return nil
 	}
+	return steps
+}
 
-	return nil
+// validateProjectName validates the project name input
+func (m Model) validateProjectName(name string) error {
+	return generator.ValidateProjectName(name)
 }
 
 // getTargetDir returns the target directory path
 func (m Model) getTargetDir() string {
+	if m.targetDir != "" {
+		return m.targetDir
+	}
 	if m.projectName == "." {
 		return "./"
 	}
 	return fmt.Sprintf("./%s/", m.projectName)
 }
 
-// generateProject creates the project using the generator
-func (m Model) generateProject() error {
-	// Determine project name and target directory
-	var projectName string
-	var targetDir string
-	var useCurrentDir bool
-
-	if m.projectName == "." {
-		// Use current directory name as project name
-		currentDir, err := generator.GetCurrentDirName()
-		if err != nil {
-			return fmt.Errorf("failed to get current directory name: %w", err)
-		}
-		projectName = currentDir
-		targetDir = "./"
-		useCurrentDir = true
-	} else {
-		projectName = m.projectName
-		targetDir = fmt.Sprintf("./%s/", m.projectName)
-		useCurrentDir = false
+// Config builds the project configuration from the collected answers
+func (m Model) Config() (generator.ProjectConfig, error) {
+	config, err := generator.NewProjectConfig(m.projectName, m.appTypeID(m.selectedAppType), m.selectedPackage, m.targetDir)
+	if err != nil {
+		return generator.ProjectConfig{}, err
 	}
 
-	// Create project configuration
-	config := generator.ProjectConfig{
-		ProjectName:   projectName,
-		ModuleName:    projectName,
-		AppType:       "web-api",
-		Package:       m.selectedPackage,
-		TargetDir:     targetDir,
-		UseCurrentDir: useCurrentDir,
+	if m.moduleName != "" {
+		config.ModuleName = m.moduleName
 	}
-
-	// Generate the project
-	return generator.Generate(config)
+	config.Vars = m.vars
+	config.Template = m.templateDir
+	config.OnConflict = m.onConflict
+	config.ConflictResolutions = m.resolutions
+	config.PostGenerate = m.postSteps
+	config.Git = m.git
+	config.Verify = m.verify
+
+	return config, nil
 }
 
 // GenerationSuccess returns true if the project was generated successfully
@@ -435,7 +786,28 @@ func (m Model) GenerationSuccess() bool {
 	return m.generationSuccess
 }
 
+// Cancelled returns true if the user quit before the project was generated,
+// or while its post-generation steps ran
+func (m Model) Cancelled() bool {
+	return m.quitting && (!m.generationSuccess || m.cancelled)
+}
+
+// GenerationError returns the error of the last generation attempt, if any.
+// Validation errors from the input stages are not reported.
+func (m Model) GenerationError() error {
+	if m.currentStage != StageSummary {
+		return nil
+	}
+	return m.generationError
+}
+
 // NewProgram creates and returns a new bubbletea program for project selection
 func NewProgram() *tea.Program {
 	return tea.NewProgram(NewModel())
 }
+
+// NewProgramWithOptions creates a bubbletea program that skips the stages
+// already answered by opts
+func NewProgramWithOptions(opts Options) *tea.Program {
+	return tea.NewProgram(NewModelWithOptions(opts))
+}
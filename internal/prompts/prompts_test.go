@@ -1,24 +1,37 @@
 package prompts
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/manuelbamise/go-ten/internal/generator"
 )
 
 func TestNewModel(t *testing.T) {
 	model := NewModel()
 
-	if model.currentStage != Stage1ProjectName {
-		t.Errorf("Expected stage %d, got %d", Stage1ProjectName, model.currentStage)
+	if model.currentStage != StageQuestions {
+		t.Errorf("Expected stage %d, got %d", StageQuestions, model.currentStage)
 	}
 
-	if len(model.appTypes) == 0 {
-		t.Error("appTypes should not be empty")
+	// Name, module path, application type and package are asked first
+	keys := []string{keyName, keyModule, keyAppType, keyPackage}
+	if len(model.steps) != len(keys) {
+		t.Fatalf("Expected %d steps, got %d", len(keys), len(model.steps))
+	}
+	for i, key := range keys {
+		if model.steps[i].Key() != key {
+			t.Errorf("Expected step %d to be %s, got %s", i, key, model.steps[i].Key())
+		}
 	}
 
-	if len(model.packages) == 0 {
-		t.Error("packages should not be empty")
+	if len(model.templates) == 0 {
+		t.Error("templates should not be empty")
 	}
 }
 
@@ -79,58 +92,353 @@ func TestGenerationSuccess(t *testing.T) {
 	}
 }
 
-func TestUpdateStage1ProjectName(t *testing.T) {
-	model := NewModel()
+// pressKeys sends each message to the model in turn
+func pressKeys(t *testing.T, model Model, msgs ...tea.KeyMsg) Model {
+	t.Helper()
+	for _, msg := range msgs {
+		updatedModel, _ := model.Update(msg)
+		model = updatedModel.(Model)
+	}
+	return model
+}
+
+// runCmds runs cmd and every command that follows from it concurrently, like
+// the bubbletea runtime, and sends the resulting messages to the model.
+// Spinner ticks are dropped so the spinner stops.
+func runCmds(t *testing.T, model Model, cmd tea.Cmd) Model {
+	t.Helper()
+	msgs := make(chan tea.Msg)
+	pending := 0
+	start := func(cmd tea.Cmd) {
+		if cmd != nil {
+			pending++
+			go func() { msgs <- cmd() }()
+		}
+	}
+	start(cmd)
+
+	timeout := time.After(30 * time.Second)
+	for pending > 0 {
+		var msg tea.Msg
+		select {
+		case msg = <-msgs:
+			pending--
+		case <-timeout:
+			t.Fatal("Commands did not finish")
+		}
+
+		switch msg := msg.(type) {
+		case nil, spinner.TickMsg, tea.QuitMsg:
+		case tea.BatchMsg:
+			for _, cmd := range msg {
+				start(cmd)
+			}
+		default:
+			updatedModel, next := model.Update(msg)
+			model = updatedModel.(Model)
+			start(next)
+		}
+	}
+	return model
+}
+
+// typeText returns the key message for typing text
+func typeText(text string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)}
+}
+
+var enter = tea.KeyMsg{Type: tea.KeyEnter}
 
-	// Test character input
-	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t', 'e', 's', 't'}}
-	updatedModel, _ := model.updateStage1(msg)
+// templateVars answers every variable of the web-api-stdlib template
+var templateVars = map[string]any{"port": 8080, "cors": true, "metrics": false, "docker": false}
 
-	if updatedModel.(Model).inputValue != "test" {
-		t.Errorf("Expected input value 'test', got '%s'", updatedModel.(Model).inputValue)
+func TestNameStepInput(t *testing.T) {
+	model := NewModel()
+
+	// Test character input, including "q" which must not quit while typing
+	model = pressKeys(t, model, typeText("test-q"))
+	if step := model.steps[0].(textStep); string(step.value) != "test-q" {
+		t.Errorf("Expected input value 'test-q', got '%s'", string(step.value))
+	}
+	if model.quitting {
+		t.Error("Typing q in a text input should not quit")
 	}
 
 	// Test backspace
-	msg = tea.KeyMsg{Type: tea.KeyBackspace}
-	updatedModel, _ = updatedModel.(Model).updateStage1(msg)
+	model = pressKeys(t, model, tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyBackspace})
+	if step := model.steps[0].(textStep); string(step.value) != "test" {
+		t.Errorf("Expected input value 'test', got '%s'", string(step.value))
+	}
 
-	if updatedModel.(Model).inputValue != "tes" {
-		t.Errorf("Expected input value 'tes', got '%s'", updatedModel.(Model).inputValue)
+	// Invalid names keep the user on the step
+	model = pressKeys(t, model, typeText(" x"), enter)
+	if model.stepIndex != 0 || model.stepError == nil {
+		t.Error("Invalid project name should show an error and stay on the step")
 	}
 }
 
-func TestUpdateStage2AppType(t *testing.T) {
+func TestQuestionFlow(t *testing.T) {
+	t.Setenv(generator.ModulePrefixEnv, "github.com/ourorg")
 	model := NewModel()
-	model.currentStage = Stage2AppType
 
-	// Test Enter key
-	msg := tea.KeyMsg{Type: tea.KeyEnter}
-	updatedModel, _ := model.Update(msg)
+	model = pressKeys(t, model, typeText("my-api"), enter)
+	if model.projectName != "my-api" {
+		t.Errorf("Expected project name 'my-api', got '%s'", model.projectName)
+	}
 
-	um := updatedModel.(Model)
-	if um.currentStage != Stage3Package {
-		t.Errorf("Expected stage %d, got %d", Stage3Package, um.currentStage)
+	// The module path defaults from the project name and is validated
+	if step := model.steps[model.stepIndex].(textStep); string(step.value) != "github.com/ourorg/my-api" {
+		t.Errorf("Expected module path default 'github.com/ourorg/my-api', got '%s'", string(step.value))
+	}
+	model = pressKeys(t, model, typeText("/"), enter)
+	if model.stepError == nil {
+		t.Error("Invalid module path should show an error")
+	}
+	model = pressKeys(t, model, tea.KeyMsg{Type: tea.KeyBackspace}, enter)
+	if model.moduleName != "github.com/ourorg/my-api" {
+		t.Errorf("Expected module path 'github.com/ourorg/my-api', got '%s'", model.moduleName)
 	}
 
-	if um.selectedAppType == "" {
-		t.Error("selectedAppType should not be empty")
+	// Select the application type and package
+	model = pressKeys(t, model, enter, enter)
+	if model.selectedAppType == "" || model.selectedPackage == "" {
+		t.Fatal("selectedAppType and selectedPackage should not be empty")
+	}
+
+	// The template's variables follow as steps
+	if model.currentStage != StageQuestions || model.steps[model.stepIndex].Key() != "port" {
+		t.Fatalf("Expected the port step, got stage %d", model.currentStage)
+	}
+	if _, ok := model.steps[model.stepIndex].(textStep); !ok {
+		t.Fatal("Expected the port step to be a number input")
+	}
+
+	// Letters are rejected by the number input
+	model = pressKeys(t, model, tea.KeyMsg{Type: tea.KeyBackspace}, typeText("x"), typeText("1"), enter)
+
+	// Keep the CORS and metrics defaults and add Docker
+	model = pressKeys(t, model, enter, enter, typeText("y"))
+
+	// The post-generation steps start from the template defaults; add the build
+	if model.currentStage != StageQuestions || model.steps[model.stepIndex].Key() != keyPostSteps {
+		t.Fatalf("Expected the post-generation step, got stage %d", model.currentStage)
+	}
+	if view := model.View(); !strings.Contains(view, "[x] go mod tidy") || !strings.Contains(view, "[ ] go build ./...") {
+		t.Errorf("Post-generation step should offer the template defaults:\n%s", view)
+	}
+	model = pressKeys(t, model, typeText("j"), typeText("j"), typeText(" "), enter)
+
+	// Creating a git repository is opt-in
+	if model.currentStage != StageQuestions || model.steps[model.stepIndex].Key() != keyGit {
+		t.Fatalf("Expected the git step, got stage %d", model.currentStage)
+	}
+	model = pressKeys(t, model, typeText("y"))
+	if model.currentStage != StageSummary {
+		t.Fatalf("Expected stage %d, got %d", StageSummary, model.currentStage)
+	}
+
+	config, err := model.Config()
+	if err != nil {
+		t.Fatalf("Config failed: %v", err)
+	}
+	if config.Vars["port"] != 8081 {
+		t.Errorf("Expected port 8081, got %v", config.Vars["port"])
+	}
+	if config.Vars["cors"] != true || config.Vars["docker"] != true {
+		t.Errorf("Unexpected confirm answers: %v", config.Vars)
+	}
+	if config.ModuleName != "github.com/ourorg/my-api" {
+		t.Errorf("Expected module path 'github.com/ourorg/my-api', got '%s'", config.ModuleName)
+	}
+	if strings.Join(config.PostGenerate, ",") != "tidy,build" {
+		t.Errorf("Expected post-generation steps tidy and build, got %v", config.PostGenerate)
+	}
+	if !config.Git.Init {
+		t.Error("Expected a git repository to be requested")
 	}
 }
 
-func TestUpdateStage3Package(t *testing.T) {
+func TestVariableSteps(t *testing.T) {
+	info := stepInfo{key: "x", title: "x"}
+	options := []option{{label: "a", value: "a"}, {label: "b", value: "b"}}
+
+	// Multi-select toggles with space
+	var step Step = newMultiSelectStep(info, options, []string{"a"})
+	step, _ = step.Update(tea.KeyMsg{Type: tea.KeyDown})
+	step, _ = step.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	step, submitted := step.Update(enter)
+	value, err := step.Submit()
+	if !submitted || err != nil || len(value.([]string)) != 2 {
+		t.Errorf("Expected both options selected, got %v (%v)", value, err)
+	}
+
+	// Confirm answers with y/n
+	step = newConfirmStep(info, false)
+	step, submitted = step.Update(typeText("y"))
+	value, _ = step.Submit()
+	if !submitted || value != true {
+		t.Errorf("Expected confirm to submit true, got %v", value)
+	}
+
+	// Single select returns the highlighted value
+	step = newSelectStep(info, options, "b")
+	value, _ = step.Submit()
+	if value != "b" {
+		t.Errorf("Expected default selection 'b', got %v", value)
+	}
+
+	// Text input edits characters, not bytes
+	step = newTextStep(info, "café")
+	step, _ = step.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	step, _ = step.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	step, _ = step.Update(typeText("ß"))
+	value, _ = step.Submit()
+	if value != "caßé" || !strings.Contains(step.View(), "> caß|é\n") {
+		t.Errorf("Expected caßé with the cursor before é, got %q:\n%s", value, step.View())
+	}
+
+	// An optional number can be left empty, a required one cannot
+	step = variableStep(generator.Variable{Name: "workers", Type: generator.VarInt})
+	if value, err := step.Submit(); value != nil || err != nil {
+		t.Errorf("Expected an empty optional number to be unset, got %v (%v)", value, err)
+	}
+	step = variableStep(generator.Variable{Name: "workers", Type: generator.VarInt, Required: true})
+	if _, err := step.Submit(); err == nil {
+		t.Error("An empty required number should be rejected")
+	}
+}
+
+func TestNewModelWithOptions(t *testing.T) {
+	// Only the name is known, so the module path is asked next
+	model := NewModelWithOptions(Options{ProjectName: "my-api"})
+	if model.steps[0].Key() != keyModule {
+		t.Errorf("Expected first step %s, got %s", keyModule, model.steps[0].Key())
+	}
+
+	// With the module path the app type is asked next
+	model = NewModelWithOptions(Options{ProjectName: "my-api", ModuleName: "example.com/my-api"})
+	if model.steps[0].Key() != keyAppType {
+		t.Errorf("Expected first step %s, got %s", keyAppType, model.steps[0].Key())
+	}
+
+	// The template is known, so only its unanswered variables, the
+	// post-generation steps and git are asked
+	model = NewModelWithOptions(Options{ProjectName: "my-api", ModuleName: "example.com/my-api", AppType: "web-api", Package: "stdlib", Vars: map[string]any{"cors": true, "metrics": true, "docker": true}})
+	if len(model.steps) != 3 || model.steps[0].Key() != "port" || model.steps[1].Key() != keyPostSteps || model.steps[2].Key() != keyGit {
+		t.Errorf("Expected the port, post-generation and git steps, got %d steps", len(model.steps))
+	}
+
+	// Everything is known, so go straight to the summary
+	model = NewModelWithOptions(Options{ProjectName: "my-api", ModuleName: "example.com/my-api", AppType: "web-api", Package: "stdlib", TargetDir: "./svc/", Vars: templateVars, PostSteps: []string{}, Git: &generator.GitOptions{}})
+	if model.currentStage != StageSummary {
+		t.Errorf("Expected stage %d, got %d", StageSummary, model.currentStage)
+	}
+	if model.selectedAppType != "Web API" {
+		t.Errorf("Expected app type 'Web API', got '%s'", model.selectedAppType)
+	}
+	if model.getTargetDir() != "./svc/" {
+		t.Errorf("Expected target dir './svc/', got '%s'", model.getTargetDir())
+	}
+}
+
+func TestCancelled(t *testing.T) {
 	model := NewModel()
-	model.currentStage = Stage3Package
 
-	// Test Enter key
-	msg := tea.KeyMsg{Type: tea.KeyEnter}
+	msg := tea.KeyMsg{Type: tea.KeyCtrlC}
+	updatedModel, _ := model.Update(msg)
+
+	if !updatedModel.(Model).Cancelled() {
+		t.Error("Cancelled should be true after quitting")
+	}
+}
+
+func TestSummaryPlanPreview(t *testing.T) {
+	model := NewModelWithOptions(Options{ProjectName: "my-api", ModuleName: "example.com/my-api", AppType: "web-api", Package: "stdlib", Vars: templateVars, PostSteps: []string{}, Git: &generator.GitOptions{}})
+
+	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}}
 	updatedModel, _ := model.Update(msg)
 
 	um := updatedModel.(Model)
-	if um.currentStage != Stage4Summary {
-		t.Errorf("Expected stage %d, got %d", Stage4Summary, um.currentStage)
+	if !um.showPlan {
+		t.Fatal("showPlan should be true after pressing p")
+	}
+	if um.planErr != nil {
+		t.Fatalf("Plan preview failed: %v", um.planErr)
+	}
+	if !strings.Contains(um.View(), "go.mod") {
+		t.Error("Summary view should show the planned files")
+	}
+}
+
+func TestConflictPrompt(t *testing.T) {
+	testDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(testDir, "go.mod"), []byte("module existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	model := NewModelWithOptions(Options{ProjectName: "my-api", ModuleName: "example.com/my-api", AppType: "web-api", Package: "stdlib", TargetDir: testDir, Vars: templateVars, PostSteps: []string{}, Git: &generator.GitOptions{}})
+
+	// Generating stops at the conflict prompt
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	um := updatedModel.(Model)
+	if um.currentStage != StageConflicts {
+		t.Fatalf("Expected stage %d, got %d", StageConflicts, um.currentStage)
 	}
 
-	if um.selectedPackage == "" {
-		t.Error("selectedPackage should not be empty")
+	// Writing a .new sibling resolves the only conflict and generates
+	updatedModel, cmd := um.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	um = runCmds(t, updatedModel.(Model), cmd)
+	if !um.GenerationSuccess() {
+		t.Fatalf("Expected generation to succeed, got error: %v", um.generationError)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(testDir, "go.mod"))
+	if string(content) != "module existing\n" {
+		t.Error("go.mod should not have been overwritten")
+	}
+	if _, err := os.Stat(filepath.Join(testDir, "go.mod.new")); err != nil {
+		t.Error("go.mod.new should have been written")
+	}
+}
+
+func TestRenderSuccessNextSteps(t *testing.T) {
+	model := NewModelWithOptions(Options{ProjectName: "my-api", ModuleName: "example.com/my-api", AppType: "web-api", Package: "stdlib", Vars: templateVars, PostSteps: []string{}, Git: &generator.GitOptions{}})
+	model.currentStage = StageSuccess
+
+	view := model.View()
+	if !strings.Contains(view, "go run ./cmd/my-api") {
+		t.Errorf("Success screen should show the template's run command:\n%s", view)
+	}
+}
+
+func TestExternalTemplateOptions(t *testing.T) {
+	t.Setenv(generator.TemplatePathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	manifest := "name: Worker\ndescription: Background worker\nappType: worker\npackage: stdlib\n"
+	if err := os.WriteFile(filepath.Join(dir, generator.ManifestFile), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// External templates are offered next to the embedded ones
+	model := NewModelWithOptions(Options{ProjectName: "my-api", ModuleName: "example.com/my-api", Template: dir})
+	view := model.View()
+	if !strings.Contains(view, "Web API") || !strings.Contains(view, "Worker") {
+		t.Errorf("App type step should list all templates:\n%s", view)
+	}
+
+	// The package step says where the template comes from
+	model = NewModelWithOptions(Options{ProjectName: "my-api", ModuleName: "example.com/my-api", AppType: "worker", Template: dir})
+	if view := model.View(); !strings.Contains(view, "(from "+dir+")") {
+		t.Errorf("Package step should show the template source:\n%s", view)
+	}
+
+	config, err := model.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Template != dir {
+		t.Errorf("Expected template directory %s, got %s", dir, config.Template)
 	}
 }
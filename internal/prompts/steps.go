@@ -0,0 +1,411 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+// Step is a single question in the prompt sequence. The sequence is built at
+// runtime from the built-in questions and the variables of the selected template.
+type Step interface {
+	// Key identifies the answer, e.g. "name" or a template variable name
+	Key() string
+	// Update handles a key press and reports whether the answer was submitted
+	Update(msg tea.KeyMsg) (Step, bool)
+	// View renders the question
+	View() string
+	// Submit returns the validated answer
+	Submit() (any, error)
+}
+
+// Keys of the built-in steps
+const (
+	keyName    = "name"
+	keyModule  = "module"
+	keyAppType = "appType"
+	keyPackage = "package"
+
+	// keyPostSteps and keyGit are no valid variable names, so they never clash with one
+	keyPostSteps = "post-generate"
+	keyGit       = "git-init"
+)
+
+// stepInfo holds what every step shares
+type stepInfo struct {
+	key         string
+	title       string
+	description string
+
+	// validate converts and checks the raw answer; nil accepts anything
+	validate func(value any) (any, error)
+}
+
+// Key returns the key the answer is stored under
+func (s stepInfo) Key() string {
+	return s.key
+}
+
+// check runs the validator on value
+func (s stepInfo) check(value any) (any, error) {
+	if s.validate == nil {
+		return value, nil
+	}
+	return s.validate(value)
+}
+
+// header renders the title and description of the step
+func (s stepInfo) header() string {
+	h := s.title + "\n"
+	if s.description != "" {
+		h += fmt.Sprintf("\x1b[2m%s\x1b[0m\n", s.description)
+	}
+	return h + "\n"
+}
+
+// textStep asks for free text, or for a whole number when numeric is set
+type textStep struct {
+	stepInfo
+	value   []rune // edited by character, so the cursor never splits one
+	cursor  int
+	numeric bool
+}
+
+// newTextStep creates a text input with an initial value
+func newTextStep(info stepInfo, value string) textStep {
+	runes := []rune(value)
+	return textStep{stepInfo: info, value: runes, cursor: len(runes)}
+}
+
+// newNumberStep creates a text input that only accepts a whole number
+func newNumberStep(info stepInfo, value string) textStep {
+	step := newTextStep(info, value)
+	step.numeric = true
+	return step
+}
+
+// Update handles key input for the text input
+func (s textStep) Update(msg tea.KeyMsg) (Step, bool) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		return s, true
+
+	case tea.KeyBackspace:
+		// Remove character at cursor position
+		if s.cursor > 0 {
+			s.value = append(s.value[:s.cursor-1:s.cursor-1], s.value[s.cursor:]...)
+			s.cursor--
+		}
+
+	case tea.KeyLeft:
+		if s.cursor > 0 {
+			s.cursor--
+		}
+
+	case tea.KeyRight:
+		if s.cursor < len(s.value) {
+			s.cursor++
+		}
+
+	case tea.KeyRunes:
+		if s.numeric && strings.Trim(string(msg.Runes), "-0123456789") != "" {
+			return s, false
+		}
+		// Add characters at cursor position
+		value := make([]rune, 0, len(s.value)+len(msg.Runes))
+		value = append(value, s.value[:s.cursor]...)
+		value = append(value, msg.Runes...)
+		s.value = append(value, s.value[s.cursor:]...)
+		s.cursor += len(msg.Runes)
+	}
+
+	return s, false
+}
+
+// View renders the input field with a cursor
+func (s textStep) View() string {
+	v := s.header() + "> "
+
+	// Display input with cursor indicator
+	for i, char := range s.value {
+		if i == s.cursor {
+			v += "|" + string(char)
+		} else {
+			v += string(char)
+		}
+	}
+
+	// Show cursor at end if at the end of input
+	if s.cursor == len(s.value) {
+		v += "|"
+	}
+
+	return v + "\n\n(Enter to submit, ctrl+c to quit)"
+}
+
+// Submit returns the entered text
+func (s textStep) Submit() (any, error) {
+	return s.check(string(s.value))
+}
+
+// option is a choice in a select or multi-select step
+type option struct {
+	label       string
+	value       string
+	description string
+}
+
+// selectStep picks exactly one option
+type selectStep struct {
+	stepInfo
+	options []option
+	cursor  int
+}
+
+// newSelectStep creates a single select with the cursor on the given value
+func newSelectStep(info stepInfo, options []option, value string) selectStep {
+	step := selectStep{stepInfo: info, options: options}
+	for i, opt := range options {
+		if opt.value == value {
+			step.cursor = i
+		}
+	}
+	return step
+}
+
+// Update handles key input for the list
+func (s selectStep) Update(msg tea.KeyMsg) (Step, bool) {
+	switch msg.String() {
+	// Navigation keys
+	case "up", "k":
+		if s.cursor > 0 {
+			s.cursor--
+		}
+
+	case "down", "j":
+		if s.cursor < len(s.options)-1 {
+			s.cursor++
+		}
+
+	case "enter":
+		return s, len(s.options) > 0
+	}
+
+	return s, false
+}
+
+// View renders the list with the cursor highlighted
+func (s selectStep) View() string {
+	v := s.header()
+	for i, opt := range s.options {
+		v += renderOption(i == s.cursor, "", opt)
+	}
+	return v + "\n(Use arrow keys to navigate, press Enter to continue, q to quit)"
+}
+
+// Submit returns the value of the highlighted option
+func (s selectStep) Submit() (any, error) {
+	if len(s.options) == 0 {
+		return nil, fmt.Errorf("nothing to select")
+	}
+	return s.check(s.options[s.cursor].value)
+}
+
+// multiSelectStep picks any number of options
+type multiSelectStep struct {
+	stepInfo
+	options  []option
+	cursor   int
+	selected map[int]bool
+}
+
+// newMultiSelectStep creates a multi-select with the given values checked
+func newMultiSelectStep(info stepInfo, options []option, values []string) multiSelectStep {
+	step := multiSelectStep{stepInfo: info, options: options, selected: map[int]bool{}}
+	for i, opt := range options {
+		for _, value := range values {
+			if opt.value == value {
+				step.selected[i] = true
+			}
+		}
+	}
+	return step
+}
+
+// Update handles key input for the list
+func (s multiSelectStep) Update(msg tea.KeyMsg) (Step, bool) {
+	switch msg.String() {
+	// Navigation keys
+	case "up", "k":
+		if s.cursor > 0 {
+			s.cursor--
+		}
+
+	case "down", "j":
+		if s.cursor < len(s.options)-1 {
+			s.cursor++
+		}
+
+	// Toggle the option under the cursor
+	case " ", "x":
+		selected := make(map[int]bool, len(s.selected))
+		for i, ok := range s.selected {
+			selected[i] = ok
+		}
+		selected[s.cursor] = !selected[s.cursor]
+		s.selected = selected
+
+	case "enter":
+		return s, true
+	}
+
+	return s, false
+}
+
+// View renders the list with check boxes
+func (s multiSelectStep) View() string {
+	v := s.header()
+	for i, opt := range s.options {
+		box := "[ ] "
+		if s.selected[i] {
+			box = "[x] "
+		}
+		v += renderOption(i == s.cursor, box, opt)
+	}
+	return v + "\n(Use arrow keys to navigate, space to toggle, Enter to continue, q to quit)"
+}
+
+// Submit returns the values of the checked options
+func (s multiSelectStep) Submit() (any, error) {
+	values := []string{}
+	for i, opt := range s.options {
+		if s.selected[i] {
+			values = append(values, opt.value)
+		}
+	}
+	return s.check(values)
+}
+
+// confirmStep asks a yes/no question
+type confirmStep struct {
+	stepInfo
+	value bool
+}
+
+// newConfirmStep creates a yes/no question with a default answer
+func newConfirmStep(info stepInfo, value bool) confirmStep {
+	return confirmStep{stepInfo: info, value: value}
+}
+
+// Update handles key input for the question
+func (s confirmStep) Update(msg tea.KeyMsg) (Step, bool) {
+	switch msg.String() {
+	case "y", "Y":
+		s.value = true
+		return s, true
+	case "n", "N":
+		s.value = false
+		return s, true
+	case "left", "right", "h", "l", "tab":
+		s.value = !s.value
+	case "enter":
+		return s, true
+	}
+	return s, false
+}
+
+// View renders the question with the current answer highlighted
+func (s confirmStep) View() string {
+	yes, no := "Yes", "No"
+	if s.value {
+		yes = fmt.Sprintf("\x1b[1m> %s\x1b[0m", yes)
+		no = "  " + no
+	} else {
+		yes = "  " + yes
+		no = fmt.Sprintf("\x1b[1m> %s\x1b[0m", no)
+	}
+	return s.header() + yes + "   " + no + "\n\n(y/n, arrow keys to change, Enter to continue, q to quit)"
+}
+
+// Submit returns the answer
+func (s confirmStep) Submit() (any, error) {
+	return s.check(s.value)
+}
+
+// renderOption renders one line of a list
+func renderOption(current bool, prefix string, opt option) string {
+	// Cursor indicator
+	cursor := " "
+	if current {
+		cursor = ">"
+	}
+
+	label := prefix + opt.label
+	if opt.description != "" {
+		label = fmt.Sprintf("%s - %s", label, opt.description)
+	}
+
+	// Highlight the current option
+	if current {
+		return fmt.Sprintf("%s \x1b[1m%s\x1b[0m\n", cursor, label)
+	}
+	return fmt.Sprintf("%s %s\n", cursor, label)
+}
+
+// variableStep builds the step asking for a template variable
+func variableStep(v generator.Variable) Step {
+	info := stepInfo{
+		key:         v.Name,
+		title:       v.Prompt,
+		description: v.Description,
+		validate: func(value any) (any, error) {
+			// An optional number left empty is unset, like an omitted --var
+			if s, ok := value.(string); ok && v.Type == generator.VarInt && !v.Required && strings.TrimSpace(s) == "" {
+				return nil, nil
+			}
+			converted, err := v.Convert(value)
+			if err != nil {
+				return nil, err
+			}
+			if v.Required && converted == "" {
+				return nil, fmt.Errorf("a value is required")
+			}
+			return converted, nil
+		},
+	}
+	if info.title == "" {
+		info.title = v.Name
+	}
+	info.title += ":"
+
+	var options []option
+	for _, choice := range v.Choices {
+		options = append(options, option{label: choice, value: choice})
+	}
+
+	// Start from the declared default
+	def, _ := v.Convert(v.Default)
+
+	switch v.Type {
+	case generator.VarBool:
+		value, _ := def.(bool)
+		return newConfirmStep(info, value)
+	case generator.VarInt:
+		value := ""
+		if v.Default != nil {
+			value = fmt.Sprint(def)
+		}
+		return newNumberStep(info, value)
+	case generator.VarSelect:
+		value, _ := def.(string)
+		return newSelectStep(info, options, value)
+	case generator.VarMultiSelect:
+		values, _ := def.([]string)
+		return newMultiSelectStep(info, options, values)
+	default:
+		value, _ := def.(string)
+		return newTextStep(info, value)
+	}
+}
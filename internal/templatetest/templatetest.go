@@ -0,0 +1,309 @@
+// Package templatetest renders templates against a matrix of answer sets and
+// compares the output with checked-in golden directories.
+//
+// A suite's golden directory holds one directory per case with the expected
+// files. Cases can be given in code or as answers files next to them:
+//
+//	testdata/web-api-stdlib/
+//	    default.yaml      answers of the "default" case
+//	    default/          files generated for it
+//	    all-features.yaml
+//	    all-features/
+package templatetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/manuelbamise/go-ten/internal/diff"
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+// DefaultCase is the case run when a suite has no cases
+const DefaultCase = "default"
+
+// Clock is the time the year and date helpers see while a suite renders
+var Clock = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Case is one set of answers a template is rendered with
+type Case struct {
+	Name   string                  // names the golden directory of the case
+	Config generator.ProjectConfig // answers; the project name and module default to sample values
+}
+
+// Suite renders one template for every case
+type Suite struct {
+	Template  string // template directory or git source; empty for the built-in templates
+	AppType   string // empty when Template holds a single template
+	Package   string
+	GoldenDir string // holds the golden directory of every case
+	Cases     []Case // when empty, the answers files in GoldenDir, or DefaultCase
+}
+
+// MismatchStatus says how a generated file differs from the golden one
+type MismatchStatus string
+
+const (
+	MismatchChanged    MismatchStatus = "changed"    // the contents differ
+	MismatchMissing    MismatchStatus = "missing"    // generated, but not in the golden directory
+	MismatchUnexpected MismatchStatus = "unexpected" // in the golden directory, but no longer generated
+)
+
+// Mismatch is a file whose output differs from the golden directory
+type Mismatch struct {
+	Path   string // slash separated, relative to the golden directory of the case
+	Status MismatchStatus
+	Diff   string // unified diff from the golden file to the output, for changed files
+}
+
+// Result is the outcome of one case
+type Result struct {
+	Case       string
+	Mismatches []Mismatch
+	Updated    bool // the golden directory was rewritten
+}
+
+// OK reports whether the output matched the golden directory
+func (r Result) OK() bool {
+	return len(r.Mismatches) == 0
+}
+
+// String summarizes the mismatches with their diffs
+func (r Result) String() string {
+	var b strings.Builder
+	for _, m := range r.Mismatches {
+		fmt.Fprintf(&b, "%s: %s\n", m.Path, m.Status)
+		b.WriteString(m.Diff)
+	}
+	return b.String()
+}
+
+// LoadCases reads a case from every YAML or JSON answers file in dir,
+// named after the file
+func LoadCases(dir string) ([]Case, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read golden directory: %w", err)
+	}
+
+	var cases []Case
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		config, err := generator.LoadAnswers(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, Case{Name: strings.TrimSuffix(entry.Name(), ext), Config: config})
+	}
+	return cases, nil
+}
+
+// cases returns the cases to run
+func (s Suite) cases() ([]Case, error) {
+	if len(s.Cases) > 0 {
+		return s.Cases, nil
+	}
+	cases, err := LoadCases(s.GoldenDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		cases = []Case{{Name: DefaultCase}}
+	}
+	return cases, nil
+}
+
+// Run renders every case and compares it with its golden directory. With
+// update set, golden directories that differ are rewritten instead.
+func (s Suite) Run(update bool) ([]Result, error) {
+	cases, err := s.cases()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, c := range cases {
+		result, err := s.runCase(c, update)
+		if err != nil {
+			return nil, fmt.Errorf("case %s: %w", c.Name, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Test runs every case as a subtest of t, failing it with the diffs of the
+// files that differ. With update set, golden directories are rewritten.
+func (s Suite) Test(t *testing.T, update bool) {
+	t.Helper()
+	cases, err := s.cases()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			result, err := s.runCase(c, update)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.OK() {
+				t.Errorf("output differs from %s (rerun with -update to accept it):\n%s", filepath.Join(s.GoldenDir, c.Name), result)
+			}
+		})
+	}
+}
+
+// runCase renders one case and compares or updates its golden directory
+func (s Suite) runCase(c Case, update bool) (Result, error) {
+	output, err := s.Render(c)
+	if err != nil {
+		return Result{}, err
+	}
+
+	dir := filepath.Join(s.GoldenDir, c.Name)
+	golden, err := readTree(dir)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Case: c.Name, Mismatches: compare(golden, output)}
+	if update && !result.OK() {
+		if err := writeTree(dir, output); err != nil {
+			return Result{}, err
+		}
+		result.Updated = true
+		result.Mismatches = nil
+	}
+	return result, nil
+}
+
+// Render returns the files the suite's template generates for a case, by
+// slash separated path. The year, date and uuid helpers are fixed, so the
+// output is the same on every run.
+func (s Suite) Render(c Case) (map[string][]byte, error) {
+	config := c.Config
+	if config.ProjectName == "" {
+		config.ProjectName = "my-project"
+	}
+	if config.ModuleName == "" {
+		config.ModuleName = "example.com/" + config.ProjectName
+	}
+	if config.AppType == "" {
+		config.AppType = s.AppType
+	}
+	if config.Package == "" {
+		config.Package = s.Package
+	}
+	if config.Template == "" {
+		config.Template = s.Template
+	}
+	// Rendered as a new project outside any git work tree, wherever the
+	// tests run, so that a git repository gets its .gitignore
+	config.TargetDir = filepath.Join(os.TempDir(), "go-ten-golden", config.ProjectName)
+
+	// A single template directory decides the app type and package
+	if config.AppType == "" && config.Template != "" {
+		templates, err := generator.LoadTemplateDir(config.Template)
+		if err != nil {
+			return nil, err
+		}
+		if len(templates) != 1 {
+			return nil, fmt.Errorf("%s holds %d templates, set the app type and package", config.Template, len(templates))
+		}
+		config.AppType, config.Package = templates[0].AppType, templates[0].Package
+	}
+
+	// Fix the helpers that depend on the time and randomness
+	config.Clock = func() time.Time { return Clock }
+	config.Random = rand.NewChaCha8([32]byte{})
+
+	plan, err := generator.DryRun(config)
+	if err != nil {
+		return nil, err
+	}
+	return plan.Contents(), nil
+}
+
+// compare lists the differences between the golden files and the output
+func compare(golden, output map[string][]byte) []Mismatch {
+	var mismatches []Mismatch
+	for path, content := range output {
+		want, ok := golden[path]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, Mismatch{Path: path, Status: MismatchMissing})
+		case !bytes.Equal(want, content):
+			mismatches = append(mismatches, Mismatch{
+				Path:   path,
+				Status: MismatchChanged,
+				Diff:   diff.Unified("golden/"+path, "output/"+path, string(want), string(content), 3),
+			})
+		}
+	}
+	for path := range golden {
+		if _, ok := output[path]; !ok {
+			mismatches = append(mismatches, Mismatch{Path: path, Status: MismatchUnexpected})
+		}
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Path < mismatches[j].Path })
+	return mismatches
+}
+
+// readTree reads every file below dir by slash separated path; a missing
+// directory has no files
+func readTree(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == dir {
+			return fs.SkipAll
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden directory: %w", err)
+	}
+	return files, nil
+}
+
+// writeTree replaces dir with the files
+func writeTree(dir string, files map[string][]byte) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove golden directory: %w", err)
+	}
+	for path, content := range files {
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create golden directory: %w", err)
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return fmt.Errorf("failed to write golden file: %w", err)
+		}
+	}
+	return nil
+}
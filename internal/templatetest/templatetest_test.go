@@ -0,0 +1,154 @@
+package templatetest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manuelbamise/go-ten/internal/generator"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden directories in testdata")
+
+// isolateTemplateDirs points the template search path and cache at empty
+// directories, so only the embedded templates are found
+func isolateTemplateDirs(t *testing.T) {
+	t.Helper()
+	t.Setenv(generator.TemplatePathEnv, t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	isolateTemplateDirs(t)
+
+	templates, err := generator.TemplatesIn("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tmpl := range templates {
+		t.Run(tmpl.ID(), func(t *testing.T) {
+			suite := Suite{AppType: tmpl.AppType, Package: tmpl.Package, GoldenDir: filepath.Join("testdata", tmpl.ID())}
+			suite.Test(t, *update)
+		})
+	}
+}
+
+func TestSuiteRun(t *testing.T) {
+	isolateTemplateDirs(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		generator.ManifestFile: "name: Worker\nappType: worker\npackage: stdlib\nvariables:\n  - name: greeting\n    default: hello\n",
+		"main.go.tmpl":         "package main\n\n// {{.ProjectName}} {{year}} {{uuid}}\nfunc main() {\n\tprintln({{quote .Vars.greeting}})\n}\n",
+		"README.md":            "# Worker\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden := t.TempDir()
+	suite := Suite{
+		Template:  dir,
+		GoldenDir: golden,
+		Cases: []Case{
+			{Name: "hello"},
+			{Name: "hi", Config: generator.ProjectConfig{ProjectName: "hi", Vars: map[string]any{"greeting": "hi"}}},
+		},
+	}
+
+	// Without golden directories every file is missing
+	results, err := suite.Run(false)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != 2 || results[0].OK() || results[0].Mismatches[0].Status != MismatchMissing {
+		t.Fatalf("Expected missing files, got %+v", results)
+	}
+
+	// Updating writes them, after which the output matches
+	if _, err := suite.Run(true); err != nil {
+		t.Fatalf("Run with update failed: %v", err)
+	}
+	main, err := os.ReadFile(filepath.Join(golden, "hi", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(main), "// hi 2000 ") || !strings.Contains(string(main), `println("hi")`) {
+		t.Errorf("Unexpected golden main.go:\n%s", main)
+	}
+	results, err = suite.Run(false)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for _, result := range results {
+		if !result.OK() {
+			t.Errorf("Expected %s to match its golden directory:\n%s", result.Case, result)
+		}
+	}
+
+	// Changed, missing and unexpected files are reported
+	main, err = os.ReadFile(filepath.Join(golden, "hello", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(golden, "hello", "main.go"), []byte(strings.Replace(string(main), "my-project", "bye", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(golden, "hello", "README.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(golden, "hello", "old.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err = suite.Run(false)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	got := map[string]MismatchStatus{}
+	for _, m := range results[0].Mismatches {
+		got[m.Path] = m.Status
+	}
+	want := map[string]MismatchStatus{"README.md": MismatchMissing, "main.go": MismatchChanged, "old.txt": MismatchUnexpected}
+	if len(got) != len(want) {
+		t.Fatalf("Expected mismatches %v, got %v", want, got)
+	}
+	for path, status := range want {
+		if got[path] != status {
+			t.Errorf("Expected %s to be %s, got %s", path, status, got[path])
+		}
+	}
+	if diff := results[0].Mismatches[1].Diff; !strings.Contains(diff, "-// bye") || !strings.Contains(diff, "+// my-project") {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+}
+
+func TestLoadCases(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "small.yaml"), []byte("projectName: small\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases, err := LoadCases(dir)
+	if err != nil {
+		t.Fatalf("LoadCases failed: %v", err)
+	}
+	if len(cases) != 1 || cases[0].Name != "small" || cases[0].Config.ProjectName != "small" {
+		t.Errorf("Unexpected cases: %+v", cases)
+	}
+
+	// A suite without answers files runs the default case
+	cases, err = Suite{GoldenDir: filepath.Join(dir, "missing")}.cases()
+	if err != nil {
+		t.Fatalf("cases failed: %v", err)
+	}
+	if len(cases) != 1 || cases[0].Name != DefaultCase {
+		t.Errorf("Expected the default case, got %+v", cases)
+	}
+}
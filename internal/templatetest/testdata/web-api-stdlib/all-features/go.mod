@@ -0,0 +1,3 @@
+module github.com/acme/all-features
+
+go 1.21
\ No newline at end of file
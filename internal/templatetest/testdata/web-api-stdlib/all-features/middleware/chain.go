@@ -0,0 +1,11 @@
+package middleware
+
+import "net/http"
+
+func ApplyMiddleware(handler http.Handler) http.Handler {
+	handler = Logging(handler)
+	handler = Metrics(handler)
+	handler = CORS(handler)
+	handler = Recovery(handler)
+	return handler
+}
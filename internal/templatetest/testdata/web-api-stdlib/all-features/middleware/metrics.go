@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"expvar"
+	"net/http"
+	"strconv"
+
+	"github.com/acme/all-features/utils"
+)
+
+var (
+	requestCount    = expvar.NewInt("http_requests_total")
+	responsesByCode = expvar.NewMap("http_responses_by_code")
+)
+
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapped := &utils.ResponseWriter{ResponseWriter: w, StatusCode: utils.StatusOK}
+
+		next.ServeHTTP(wrapped, r)
+
+		requestCount.Add(1)
+		responsesByCode.Add(strconv.Itoa(wrapped.StatusCode), 1)
+	})
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type ResponseWriter struct {
+	http.ResponseWriter
+	StatusCode int
+}
+
+func (rw *ResponseWriter) WriteHeader(code int) {
+	rw.StatusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func WriteJSON(w http.ResponseWriter, status int, data interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	return json.NewEncoder(w).Encode(data)
+}
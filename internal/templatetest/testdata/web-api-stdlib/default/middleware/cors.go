@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"example.com/my-project/utils"
+)
+
+func CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(utils.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"example.com/my-project/utils"
+)
+
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Printf("Panic recovered: %v", err)
+				utils.WriteJSON(w, utils.StatusInternalError, map[string]string{"error": "Internal server error"})
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,3 @@
+module github.com/acme/minimal
+
+go 1.21
\ No newline at end of file
@@ -0,0 +1,9 @@
+package middleware
+
+import "net/http"
+
+func ApplyMiddleware(handler http.Handler) http.Handler {
+	handler = Logging(handler)
+	handler = Recovery(handler)
+	return handler
+}